@@ -1,3 +1,5 @@
 module github.com/nperez-messagebird/sctp
 
+go 1.16
+
 require golang.org/x/sys v0.0.0-20190415081028-16da32be82c5
@@ -0,0 +1,548 @@
+package sctp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AssociationManager keeps a table of the live associations of a OneToMany
+// listener and hands each of them out as an *Association, which reads and
+// writes the messages of that association only.
+//
+// The manager reads the listening socket itself: SCTP_ASSOC_CHANGE and
+// SCTP_SHUTDOWN_EVENT notifications keep the table up to date, and data is
+// queued on the handle of the association it belongs to. Notifications are
+// passed on to the listener's notification channel if enabled, always as
+// under NotificationDrop: waiting for the consumer would hold up all the
+// associations. The listener must not be read other than through the
+// manager, and takes no read deadline.
+type AssociationManager struct {
+	ln        *SCTPListener
+	queueSize int
+
+	mu      sync.Mutex
+	assocs  map[AssocID]*Association
+	pending []*Association
+	err     error
+
+	ready chan struct{}
+	done  chan struct{}
+}
+
+// AssociationInfo describes an association tracked by an
+// AssociationManager.
+type AssociationInfo struct {
+	ID              AssocID
+	PeerAddr        *SCTPAddr
+	InboundStreams  uint16
+	OutboundStreams uint16
+	State           AssocState
+	Started         time.Time
+}
+
+// Association is a handle on one association of an AssociationManager.
+type Association struct {
+	m  *AssociationManager
+	id AssocID
+
+	mu   sync.Mutex
+	info AssociationInfo
+
+	msgs    chan *assocMsg
+	gone    chan struct{}
+	dropped uint64
+
+	// rmu serializes reads and guards cur, the message being read
+	rmu sync.Mutex
+	cur *assocMsg
+}
+
+type assocMsg struct {
+	data []byte
+	info *RcvInfo
+}
+
+// partialKey identifies a message being reassembled. With
+// SCTP_FRAGMENT_INTERLEAVE at 2, which I-DATA interleaving requires, the
+// pieces of messages of different streams of an association, and of
+// ordered and unordered messages of a stream, come in mixed.
+type partialKey struct {
+	id        AssocID
+	stream    uint16
+	unordered bool
+}
+
+// reassembly holds the messages read in part.
+type reassembly map[partialKey]*assocMsg
+
+// add appends b to the message described by info, and returns the message
+// once eor marks its end.
+func (r reassembly) add(info *RcvInfo, b []byte, eor bool) *assocMsg {
+	key := partialKey{
+		id:        info.AssocID,
+		stream:    info.Stream,
+		unordered: info.Flags&SCTP_UNORDERED != 0,
+	}
+	msg := r[key]
+	if msg == nil {
+		msg = &assocMsg{info: info}
+	}
+	msg.data = append(msg.data, b...)
+	if !eor {
+		r[key] = msg
+		return nil
+	}
+	delete(r, key)
+	return msg
+}
+
+// drop discards the messages of the association read in part.
+func (r reassembly) drop(id AssocID) {
+	for key := range r {
+		if key.id == id {
+			delete(r, key)
+		}
+	}
+}
+
+// NewAssociationManager starts managing the associations of ln, which
+// must be a OneToMany listener. Up to queueSize messages are queued per
+// association. Messages arriving while the queue of their association is
+// full are dropped and counted in Dropped: waiting for the handle to be
+// read would hold up all the other associations of the socket.
+func NewAssociationManager(ln *SCTPListener, queueSize int) (*AssociationManager, error) {
+	if ln.socketMode != OneToMany {
+		return nil, fmt.Errorf("AssociationManager requires a OneToMany socket")
+	}
+	if ln.peelOff != nil {
+		return nil, fmt.Errorf("AssociationManager cannot be used with AutoPeelOff")
+	}
+	if queueSize <= 0 {
+		return nil, fmt.Errorf("queue size must be positive")
+	}
+	if ln.fd.isNonblocking() {
+		return nil, fmt.Errorf("AssociationManager requires a blocking socket")
+	}
+	if !atomic.CompareAndSwapInt32(&ln.managed, 0, 1) {
+		return nil, fmt.Errorf("listener already managed")
+	}
+	// a read deadline, even one long expired, would end the reads of
+	// the manager
+	if err := ln.fd.setReadDeadline(time.Time{}); err != nil {
+		atomic.StoreInt32(&ln.managed, 0)
+		return nil, err
+	}
+	var ids []AssocID
+	err := ln.fd.control(func(fd int) (err error) {
+		if err = SCTPSetEvent(fd, SCTP_FUTURE_ASSOC, SCTP_ASSOC_CHANGE, true); err != nil {
+			return err
+		}
+		if err = SCTPSetEvent(fd, SCTP_FUTURE_ASSOC, SCTP_SHUTDOWN_EVENT, true); err != nil {
+			return err
+		}
+		if err = SCTPSetRecvRcvInfo(fd, true); err != nil {
+			return err
+		}
+		ids, err = SCTPGetAssocIDList(fd)
+		return err
+	})
+	if err != nil {
+		atomic.StoreInt32(&ln.managed, 0)
+		return nil, err
+	}
+
+	m := newAssociationManager(ln, queueSize)
+	for _, id := range ids {
+		m.add(m.describe(id))
+	}
+	go m.run()
+	return m, nil
+}
+
+func newAssociationManager(ln *SCTPListener, queueSize int) *AssociationManager {
+	return &AssociationManager{
+		ln:        ln,
+		queueSize: queueSize,
+		assocs:    make(map[AssocID]*Association),
+		ready:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// Accept waits for and returns the next association.
+func (m *AssociationManager) Accept() (*Association, error) {
+	return m.AcceptContext(context.Background())
+}
+
+// AcceptContext acts like Accept but gives up waiting once ctx is done,
+// returning ctx.Err().
+func (m *AssociationManager) AcceptContext(ctx context.Context) (*Association, error) {
+	for {
+		m.mu.Lock()
+		if len(m.pending) > 0 {
+			a := m.pending[0]
+			m.pending[0] = nil
+			m.pending = m.pending[1:]
+			if len(m.pending) > 0 {
+				// ready holds a single wakeup for any number of
+				// additions, so pass it on to the next waiter
+				m.signalReady()
+			}
+			m.mu.Unlock()
+			return a, nil
+		}
+		err := m.err
+		m.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-m.ready:
+		case <-m.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Association returns the handle of the association with the given ID, or
+// nil if it is not live.
+func (m *AssociationManager) Association(id AssocID) *Association {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.assocs[id]
+}
+
+// Associations returns the live associations.
+func (m *AssociationManager) Associations() []*Association {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assocs := make([]*Association, 0, len(m.assocs))
+	for _, a := range m.assocs {
+		assocs = append(assocs, a)
+	}
+	return assocs
+}
+
+// Close stops the manager and closes the listener, which ends all of its
+// associations.
+func (m *AssociationManager) Close() error {
+	err := m.ln.Close()
+	<-m.done
+	return err
+}
+
+func (m *AssociationManager) run() {
+	var err error
+	defer func() {
+		m.mu.Lock()
+		if errors.Is(err, net.ErrClosed) || err == nil {
+			err = net.ErrClosed
+		}
+		m.err = err
+		for id, a := range m.assocs {
+			delete(m.assocs, id)
+			close(a.gone)
+		}
+		m.mu.Unlock()
+		close(m.done)
+	}()
+
+	b := make([]byte, 64*1024)
+	partial := make(reassembly)
+	var notif []byte
+	for {
+		var (
+			n, flags int
+			info     *RecvMsgInfo
+		)
+		err = m.ln.fd.read(context.Background(), func(s int) (err error) {
+			n, info, flags, err = SCTPRecvMsg(s, b)
+			return
+		})
+		if err != nil {
+			return
+		}
+
+		if flags&MSG_NOTIFICATION != 0 {
+			notif = append(notif, b[:n]...)
+			if flags&MSG_EOR == 0 {
+				continue
+			}
+			err = m.notification(notif, partial)
+			notif = nil
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		if info.RcvInfo == nil {
+			// cannot tell which association the data belongs to
+			continue
+		}
+		msg := partial.add(info.RcvInfo, b[:n], flags&MSG_EOR != 0)
+		if msg == nil {
+			continue
+		}
+
+		id := msg.info.AssocID
+		a := m.Association(id)
+		if a == nil {
+			// set up before the manager, or its SCTP_COMM_UP was
+			// missed
+			a = m.add(m.describe(id))
+		}
+		a.queue(msg)
+	}
+}
+
+// notification updates the table from the notification in b, drops the
+// messages of associations that are gone from partial and passes the
+// notification on to the listener's notification channel.
+func (m *AssociationManager) notification(b []byte, partial reassembly) error {
+	ntf := m.ln.fd.notifications()
+	n, err := SCTPParseNotification(b)
+	if err != nil {
+		if ntf != nil {
+			atomic.AddUint64(&ntf.dropped, 1)
+		}
+		return nil
+	}
+
+	switch e := n.event.(type) {
+	case *AssociationChange:
+		switch e.State {
+		case SCTP_COMM_UP:
+			info := m.describe(e.AssocID)
+			info.InboundStreams = e.InboundStreams
+			info.OutboundStreams = e.OutboundStreams
+			m.add(info)
+		case SCTP_RESTART:
+			// the peer lost what it was sending
+			partial.drop(e.AssocID)
+			if a := m.Association(e.AssocID); a != nil {
+				a.mu.Lock()
+				a.info.InboundStreams = e.InboundStreams
+				a.info.OutboundStreams = e.OutboundStreams
+				a.info.State = SCTP_ESTABLISHED
+				a.mu.Unlock()
+			}
+		case SCTP_COMM_LOST, SCTP_SHUTDOWN_COMP, SCTP_CANT_STR_ASSOC:
+			partial.drop(e.AssocID)
+			m.remove(e.AssocID)
+		}
+	case *Shutdown:
+		if a := m.Association(e.AssocID); a != nil {
+			a.setState(SCTP_SHUTDOWN_RECEIVED)
+		}
+	}
+
+	if ntf != nil {
+		ntf.track(n)
+		return ntf.offer(n)
+	}
+	return nil
+}
+
+// describe looks up the peer addresses and stream counts of the
+// association. Failures leave the fields empty.
+func (m *AssociationManager) describe(id AssocID) AssociationInfo {
+	info := AssociationInfo{
+		ID:      id,
+		State:   SCTP_ESTABLISHED,
+		Started: time.Now(),
+	}
+	if raddr, err := m.ln.SCTPRemoteAddr(id); err == nil {
+		info.PeerAddr = raddr
+	}
+	if status, err := m.ln.Status(id); err == nil {
+		info.State = status.State
+		info.InboundStreams = status.InboundStreams
+		info.OutboundStreams = status.OutboundStreams
+	}
+	return info
+}
+
+// add enters an association in the table and queues it for Accept. An
+// association already in the table is left as it is.
+func (m *AssociationManager) add(info AssociationInfo) *Association {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if a, ok := m.assocs[info.ID]; ok {
+		return a
+	}
+	a := &Association{
+		m:    m,
+		id:   info.ID,
+		info: info,
+		msgs: make(chan *assocMsg, m.queueSize),
+		gone: make(chan struct{}),
+	}
+	m.assocs[info.ID] = a
+	m.pending = append(m.pending, a)
+	m.signalReady()
+	return a
+}
+
+// signalReady wakes up an Accept waiting for an association.
+func (m *AssociationManager) signalReady() {
+	select {
+	case m.ready <- struct{}{}:
+	default:
+	}
+}
+
+// remove drops an association from the table. Its handle still returns
+// the messages queued before io.EOF.
+func (m *AssociationManager) remove(id AssocID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.assocs[id]
+	if !ok {
+		return
+	}
+	delete(m.assocs, id)
+	a.setState(SCTP_CLOSED)
+	close(a.gone)
+}
+
+// ID returns the ID of the association on the listening socket.
+func (a *Association) ID() AssocID {
+	return a.id
+}
+
+// Info returns what is known of the association.
+func (a *Association) Info() AssociationInfo {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.info
+}
+
+// Status queries the kernel for the current state of the association.
+func (a *Association) Status() (*AssocStatus, error) {
+	return a.m.ln.Status(a.id)
+}
+
+// Dropped returns the number of messages of the association that were
+// discarded because its queue was full.
+func (a *Association) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// queue hands msg to the readers of the association, or drops it if the
+// queue is full.
+func (a *Association) queue(msg *assocMsg) {
+	select {
+	case a.msgs <- msg:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+func (a *Association) setState(state AssocState) {
+	a.mu.Lock()
+	a.info.State = state
+	a.mu.Unlock()
+}
+
+// Read reads the next message of the association, or part of it if b is
+// too small; the rest is returned by the following reads. Once the
+// association is gone and its queued messages are read, Read returns
+// io.EOF.
+func (a *Association) Read(b []byte) (int, error) {
+	n, _, _, err := a.ReadMsgContext(context.Background(), b)
+	return n, err
+}
+
+// ReadMsg acts like Read and returns the SCTP_RCVINFO of the message, and
+// MSG_EOR in flags once the end of the message has been read.
+func (a *Association) ReadMsg(b []byte) (int, *RcvInfo, int, error) {
+	return a.ReadMsgContext(context.Background(), b)
+}
+
+// ReadMsgContext acts like ReadMsg but gives up waiting for a message once
+// ctx is done, returning ctx.Err().
+func (a *Association) ReadMsgContext(ctx context.Context, b []byte) (int, *RcvInfo, int, error) {
+	a.rmu.Lock()
+	defer a.rmu.Unlock()
+	if a.cur == nil {
+		msg, err := a.next(ctx)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		a.cur = msg
+	}
+	msg := a.cur
+	n := copy(b, msg.data)
+	msg.data = msg.data[n:]
+	var flags int
+	if len(msg.data) == 0 {
+		flags = MSG_EOR
+		a.cur = nil
+	}
+	return n, msg.info, flags, nil
+}
+
+func (a *Association) next(ctx context.Context) (*assocMsg, error) {
+	select {
+	case msg := <-a.msgs:
+		return msg, nil
+	default:
+	}
+	select {
+	case msg := <-a.msgs:
+		return msg, nil
+	case <-a.gone:
+		// messages queued before the association went away are
+		// still handed out
+		select {
+		case msg := <-a.msgs:
+			return msg, nil
+		default:
+			return nil, io.EOF
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Write sends b as one message on stream 0 of the association.
+func (a *Association) Write(b []byte) (int, error) {
+	return a.WriteMsg(b, nil)
+}
+
+// WriteMsg sends b as one message described by info, which may be nil.
+// The association ID of info is set to the one of a.
+func (a *Association) WriteMsg(b []byte, info *SndInfo) (int, error) {
+	var i SndInfo
+	if info != nil {
+		i = *info
+	}
+	i.AssocID = a.id
+	return a.m.ln.SendMsg(b, &i)
+}
+
+// Close shuts the association down gracefully. It is removed from the
+// table once SCTP_SHUTDOWN_COMP is received.
+func (a *Association) Close() error {
+	_, err := a.m.ln.SendMsg(nil, &SndInfo{AssocID: a.id, Flags: SCTP_EOF})
+	if err == nil {
+		a.setState(SCTP_SHUTDOWN_PENDING)
+	}
+	return err
+}
+
+// Abort ends the association right away with an ABORT chunk. It is
+// removed from the table once SCTP_COMM_LOST is received.
+func (a *Association) Abort() error {
+	_, err := a.m.ln.SendMsg(nil, &SndInfo{AssocID: a.id, Flags: SCTP_ABORT})
+	return err
+}
@@ -0,0 +1,310 @@
+package sctp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrNoPeerAuth is passed to KeyRotator.ErrorHandler for associations
+// whose peer does not support SCTP-AUTH.
+var ErrNoPeerAuth = errors.New("peer does not support SCTP-AUTH")
+
+// KeyRotator replaces the SCTP-AUTH shared key of a socket at a fixed
+// interval. Keys are numbered after the interval they belong to, counted
+// from the Unix epoch, so two ends using the same Interval and Key agree on
+// the key in use without talking to each other.
+//
+// At the start of every interval the new key is installed, and
+// ActivationDelay later it becomes the active key while the previous one is
+// deactivated. This relies on the clocks of both ends being within
+// ActivationDelay of each other: an end that has not installed the new key
+// by the time the other one sends with it drops the authenticated chunks it
+// receives. Once the peer is seen sending with the new key, reported by
+// SCTP_AUTH_NEW_KEY, it is made active on that association right away.
+// With WaitForPeer set, that is the only way a key becomes active, which
+// does not depend on the clocks at all.
+//
+// Deactivated keys are deleted once the kernel indicates with
+// SCTP_AUTH_FREE_KEY that no chunk in flight depends on them any more.
+// All of this requires the socket's notifications to be fed to
+// HandleNotification.
+//
+// On a OneToMany socket the keys of the endpoint and of all of its
+// associations are rotated at once, using SCTP_ALL_ASSOC; this needs
+// Linux 5.1 or later.
+type KeyRotator struct {
+	// Interval is the lifetime of a key.
+	Interval time.Duration
+
+	// ActivationDelay is the time between installing a key and making it
+	// active, which gives the peer time to install it as well.
+	ActivationDelay time.Duration
+
+	// WaitForPeer keeps the previous key active until the peer has sent a
+	// chunk authenticated with the new one, and ActivationDelay is not
+	// used. The peer has to switch on its own, so at most one end may set
+	// WaitForPeer.
+	WaitForPeer bool
+
+	// Key returns the shared key with the given number. Both ends must
+	// return the same key for the same number.
+	Key func(keyNumber uint16) ([]byte, error)
+
+	// ErrorHandler, if not nil, is called with the errors met while
+	// rotating keys in the background.
+	ErrorHandler func(assocID AssocID, err error)
+
+	conn   *SCTPConn
+	target AssocID
+
+	mu      sync.Mutex
+	active  uint16
+	pending uint16
+	retired map[uint16]bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Start installs the key of the current interval on c and keeps rotating
+// keys until Stop is called. Notifications of type
+// SCTP_AUTHENTICATION_INDICATION are turned on.
+func (r *KeyRotator) Start(c *SCTPConn) error {
+	if r.Interval <= 0 {
+		return errors.New("key rotation interval must be positive")
+	}
+	if r.Key == nil {
+		return errors.New("no key function")
+	}
+	if r.stop != nil {
+		return errors.New("key rotation already started")
+	}
+	mode, err := c.GetSocketMode()
+	if err != nil {
+		return err
+	}
+	r.conn = c
+	r.target = 0
+	if mode == OneToMany {
+		r.target = SCTP_ALL_ASSOC
+	}
+	if err := c.Subscribe(r.target, SCTP_AUTHENTICATION_INDICATION, true); err != nil {
+		return err
+	}
+	current := r.target
+	if mode == OneToMany {
+		current = SCTP_FUTURE_ASSOC
+	}
+	if r.active, err = c.GetActiveKey(current); err != nil {
+		return err
+	}
+	r.retired = make(map[uint16]bool)
+	keyNumber := r.keyNumber(time.Now())
+	if err := r.install(keyNumber); err != nil {
+		return err
+	}
+
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run()
+	return nil
+}
+
+// Stop ends the rotation. The installed keys are left in place.
+func (r *KeyRotator) Stop() {
+	if r.stop == nil {
+		return
+	}
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+// HandleNotification makes the newest key active on an association whose
+// peer is reported by SCTP_AUTH_NEW_KEY to use it, deletes the retired keys
+// reported as no longer in use by SCTP_AUTH_FREE_KEY and reports
+// SCTP_AUTH_NO_AUTH as ErrNoPeerAuth. Notifications of other types are
+// ignored.
+func (r *KeyRotator) HandleNotification(n *Notification) {
+	a := n.GetAuthentication()
+	if a == nil {
+		return
+	}
+	switch a.Indication {
+	case SCTP_AUTH_NEW_KEY:
+		r.mu.Lock()
+		pending := r.pending == a.KeyNumber && r.pending != r.active && !r.retired[a.KeyNumber]
+		r.mu.Unlock()
+		if !pending {
+			return
+		}
+		if err := r.activatePeer(a.AssocID, a.KeyNumber); err != nil {
+			r.fail(a.AssocID, err)
+		}
+	case SCTP_AUTH_FREE_KEY:
+		r.mu.Lock()
+		retired := r.retired[a.KeyNumber]
+		r.mu.Unlock()
+		if !retired {
+			return
+		}
+		if err := r.conn.DeleteKey(a.AssocID, a.KeyNumber); err != nil {
+			r.fail(a.AssocID, err)
+		}
+	case SCTP_AUTH_NO_AUTH:
+		r.fail(a.AssocID, ErrNoPeerAuth)
+	}
+}
+
+// keyNumber returns the number of the key of the interval t falls in.
+func (r *KeyRotator) keyNumber(t time.Time) uint16 {
+	return uint16(t.UnixNano() / int64(r.Interval))
+}
+
+// untilNext returns the time left until the interval following t.
+func (r *KeyRotator) untilNext(t time.Time) time.Duration {
+	return r.Interval - time.Duration(t.UnixNano()%int64(r.Interval))
+}
+
+func (r *KeyRotator) run() {
+	defer close(r.done)
+
+	rotate := time.NewTimer(r.untilNext(time.Now()))
+	defer rotate.Stop()
+	activate := time.NewTimer(r.ActivationDelay)
+	defer activate.Stop()
+	if r.WaitForPeer {
+		activate.Stop()
+	}
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-rotate.C:
+			now := time.Now()
+			rotate.Reset(r.untilNext(now))
+			keyNumber := r.keyNumber(now)
+			if err := r.install(keyNumber); err != nil {
+				if r.fail(r.target, err) {
+					return
+				}
+				continue
+			}
+			if r.WaitForPeer {
+				continue
+			}
+			if !activate.Stop() {
+				select {
+				case <-activate.C:
+				default:
+				}
+			}
+			activate.Reset(r.ActivationDelay)
+		case <-activate.C:
+			r.mu.Lock()
+			pending := r.pending
+			r.mu.Unlock()
+			if err := r.activate(pending); err != nil && r.fail(r.target, err) {
+				return
+			}
+		}
+	}
+}
+
+func (r *KeyRotator) install(keyNumber uint16) error {
+	key, err := r.Key(keyNumber)
+	if err != nil {
+		return err
+	}
+	if err := r.conn.SetAuthKey(r.target, keyNumber, key); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	delete(r.retired, keyNumber)
+	r.pending = keyNumber
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *KeyRotator) activate(keyNumber uint16) error {
+	r.mu.Lock()
+	old := r.active
+	r.mu.Unlock()
+	if old == keyNumber {
+		return nil
+	}
+	if err := r.conn.SetActiveKey(r.target, keyNumber); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.active = keyNumber
+	r.retired[old] = true
+	r.mu.Unlock()
+	if err := r.conn.DeactivateKey(r.target, old); err != nil {
+		return err
+	}
+	if r.target == SCTP_ALL_ASSOC {
+		// the endpoint holds no chunks in flight, so its copy of the
+		// key can go right away
+		return r.conn.DeleteKey(SCTP_FUTURE_ASSOC, old)
+	}
+	return nil
+}
+
+// activatePeer makes keyNumber active on the association whose peer has
+// been seen using it. On a OneToMany socket under WaitForPeer the endpoint
+// moves on to it as well, so that new associations start with it.
+func (r *KeyRotator) activatePeer(assocID AssocID, keyNumber uint16) error {
+	old, err := r.conn.GetActiveKey(assocID)
+	if err != nil || old == keyNumber {
+		return err
+	}
+	if err := r.conn.SetActiveKey(assocID, keyNumber); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.retired[old] = true
+	r.mu.Unlock()
+	if err := r.conn.DeactivateKey(assocID, old); err != nil {
+		return err
+	}
+
+	if r.target != SCTP_ALL_ASSOC {
+		// the association is all there is
+		r.mu.Lock()
+		r.active = keyNumber
+		r.mu.Unlock()
+		return nil
+	}
+	if !r.WaitForPeer {
+		// the endpoint and the other associations follow when
+		// ActivationDelay is over
+		return nil
+	}
+	r.mu.Lock()
+	old = r.active
+	r.active = keyNumber
+	r.mu.Unlock()
+	if old == keyNumber {
+		return nil
+	}
+	if err := r.conn.SetActiveKey(SCTP_FUTURE_ASSOC, keyNumber); err != nil {
+		return err
+	}
+	return r.conn.DeleteKey(SCTP_FUTURE_ASSOC, old)
+}
+
+// fail reports err and tells whether the rotation has to end because the
+// socket is closed.
+func (r *KeyRotator) fail(assocID AssocID, err error) bool {
+	if r.ErrorHandler != nil {
+		r.ErrorHandler(assocID, err)
+	}
+	return errors.Is(err, net.ErrClosed)
+}
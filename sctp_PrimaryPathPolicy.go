@@ -0,0 +1,64 @@
+package sctp
+
+import (
+	"net"
+)
+
+// PrimaryPathPolicy keeps a preferred peer address primary. The kernel
+// moves traffic to another path when the primary one fails, but does not
+// move it back; whenever SCTP_PEER_ADDR_CHANGE reports the preferred
+// address as available again, the policy makes it primary.
+//
+// Notifications are not read by the policy itself: they have to be passed
+// to HandleNotification, for instance from the loop draining
+// SCTPConn.Notifications. On a OneToMany socket every association with the
+// preferred address among its peer addresses is handled.
+type PrimaryPathPolicy struct {
+	// ErrorHandler, if not nil, is called when the preferred address
+	// cannot be made primary.
+	ErrorHandler func(assocID AssocID, err error)
+
+	conn      *SCTPConn
+	preferred net.IP
+}
+
+// NewPrimaryPathPolicy returns a policy keeping preferred primary on c,
+// and turns on the SCTP_PEER_ADDR_CHANGE notifications it relies on.
+func NewPrimaryPathPolicy(c *SCTPConn, preferred net.IP) (*PrimaryPathPolicy, error) {
+	mode, err := c.GetSocketMode()
+	if err != nil {
+		return nil, err
+	}
+	var assocID AssocID
+	if mode == OneToMany {
+		assocID = SCTP_ALL_ASSOC
+	}
+	if err := c.Subscribe(assocID, SCTP_PEER_ADDR_CHANGE, true); err != nil {
+		return nil, err
+	}
+	return &PrimaryPathPolicy{
+		conn:      c,
+		preferred: preferred,
+	}, nil
+}
+
+// HandleNotification makes the preferred address primary again if n
+// reports that it is available. Other notifications are ignored.
+func (p *PrimaryPathPolicy) HandleNotification(n *Notification) {
+	e := n.GetPeerAddrChange()
+	if e == nil || !e.Addr.IP.Equal(p.preferred) {
+		return
+	}
+	switch e.State {
+	case SCTP_ADDR_AVAILABLE, SCTP_ADDR_CONFIRMED:
+	default:
+		return
+	}
+	raddr := &SCTPAddr{
+		IPAddrs: []net.IPAddr{e.Addr},
+		Port:    e.Port,
+	}
+	if err := p.conn.SetPrimaryPeerAddr(e.AssocID, raddr); err != nil && p.ErrorHandler != nil {
+		p.ErrorHandler(e.AssocID, err)
+	}
+}
@@ -2,6 +2,7 @@ package sctp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"strconv"
@@ -82,6 +83,104 @@ func ResolveSCTPAddr(addressFamily SCTPAddressFamily, addrs string) (*SCTPAddr,
 	}, nil
 }
 
+// parseSockaddr decodes the sockaddr_in or sockaddr_in6 at the start of b.
+func parseSockaddr(b []byte) (net.IPAddr, int, error) {
+	if len(b) < 2 {
+		return net.IPAddr{}, 0, fmt.Errorf("short sockaddr: %d bytes", len(b))
+	}
+	switch family := nativeEndian.Uint16(b); family {
+	case syscall.AF_INET:
+		if len(b) < syscall.SizeofSockaddrInet4 {
+			return net.IPAddr{}, 0, fmt.Errorf("short sockaddr_in: %d bytes", len(b))
+		}
+		ip := make(net.IP, net.IPv4len)
+		copy(ip, b[4:8])
+		return net.IPAddr{IP: ip}, int(binary.BigEndian.Uint16(b[2:])), nil
+	case syscall.AF_INET6:
+		if len(b) < syscall.SizeofSockaddrInet6 {
+			return net.IPAddr{}, 0, fmt.Errorf("short sockaddr_in6: %d bytes", len(b))
+		}
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, b[8:24])
+		var zone string
+		if scope := nativeEndian.Uint32(b[24:]); scope != 0 {
+			if ifi, err := net.InterfaceByIndex(int(scope)); err == nil {
+				zone = ifi.Name
+			}
+		}
+		return net.IPAddr{IP: ip, Zone: zone}, int(binary.BigEndian.Uint16(b[2:])), nil
+	default:
+		return net.IPAddr{}, 0, fmt.Errorf("unknown address family: %d", family)
+	}
+}
+
+// sockaddrStorage encodes the first address of addr in a sockaddr_storage.
+// A nil or empty addr yields the zero sockaddr_storage.
+func sockaddrStorage(addr *SCTPAddr) (st [128]byte) {
+	if addr == nil || len(addr.IPAddrs) == 0 {
+		return
+	}
+	one := &SCTPAddr{
+		IPAddrs: addr.IPAddrs[:1],
+		Port:    addr.Port,
+	}
+	copy(st[:], one.ToRawSockAddrBuf())
+	return
+}
+
+// sockaddr returns the first address of a as the destination of a
+// sendmsg call.
+func (a *SCTPAddr) sockaddr() (syscall.Sockaddr, error) {
+	if a == nil || len(a.IPAddrs) == 0 {
+		return nil, &net.AddrError{Err: "missing address"}
+	}
+	ip := a.IPAddrs[0]
+	if ip4 := ip.IP.To4(); ip4 != nil {
+		sa := &syscall.SockaddrInet4{Port: a.Port}
+		copy(sa.Addr[:], ip4)
+		return sa, nil
+	}
+	ip6 := ip.IP.To16()
+	if ip6 == nil {
+		return nil, &net.AddrError{Err: "invalid IP address", Addr: ip.String()}
+	}
+	sa := &syscall.SockaddrInet6{Port: a.Port}
+	if ifi, err := net.InterfaceByName(ip.Zone); err == nil {
+		sa.ZoneId = uint32(ifi.Index)
+	}
+	copy(sa.Addr[:], ip6)
+	return sa, nil
+}
+
+// sockaddrToSCTPAddr converts the source address returned by recvmsg.
+func sockaddrToSCTPAddr(sa syscall.Sockaddr) *SCTPAddr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		ip := make(net.IP, net.IPv4len)
+		copy(ip, sa.Addr[:])
+		return &SCTPAddr{
+			IPAddrs:       []net.IPAddr{{IP: ip}},
+			Port:          sa.Port,
+			AddressFamily: SCTP4,
+		}
+	case *syscall.SockaddrInet6:
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, sa.Addr[:])
+		var zone string
+		if sa.ZoneId != 0 {
+			if ifi, err := net.InterfaceByIndex(int(sa.ZoneId)); err == nil {
+				zone = ifi.Name
+			}
+		}
+		return &SCTPAddr{
+			IPAddrs:       []net.IPAddr{{IP: ip, Zone: zone}},
+			Port:          sa.Port,
+			AddressFamily: SCTP6,
+		}
+	}
+	return nil
+}
+
 func (a *SCTPAddr) Network() string { return "sctp" }
 
 func (a *SCTPAddr) ToRawSockAddrBuf() []byte {
@@ -175,3 +274,73 @@ func SCTPAddrFamily(laddr *SCTPAddr, raddr *SCTPAddr) (family int, ipv6only bool
 
 	return SCTP4.ToSyscall(), false
 }
+
+// sctpNetworkFamily returns the socket address family for network, which
+// must be one of "sctp", "sctp4" or "sctp6". For "sctp" the family is
+// picked from the addresses as resolved by resolveSCTPNetworkAddr, either
+// of which may be nil; only SCTP6Only addresses make the socket IPv6-only.
+func sctpNetworkFamily(network string, laddr, raddr *SCTPAddr) (family int, ipv6only bool, err error) {
+	switch network {
+	case "sctp":
+		if laddr == nil {
+			laddr = raddr
+		} else if raddr == nil {
+			raddr = laddr
+		}
+		if laddr != nil && laddr.AddressFamily == raddr.AddressFamily {
+			return laddr.AddressFamily.ToSyscall(), laddr.AddressFamily == SCTP6Only, nil
+		}
+		family, _ = SCTPAddrFamily(laddr, raddr)
+		return family, false, nil
+	case "sctp4":
+		return syscall.AF_INET, false, nil
+	case "sctp6":
+		return syscall.AF_INET6, true, nil
+	default:
+		return 0, false, net.UnknownNetworkError(network)
+	}
+}
+
+// resolveSCTPNetworkAddr resolves address for one of the "sctp", "sctp4"
+// or "sctp6" networks.
+func resolveSCTPNetworkAddr(network, address string) (*SCTPAddr, error) {
+	switch network {
+	case "sctp4":
+		return ResolveSCTPAddr(SCTP4, address)
+	case "sctp6":
+		addr, err := ResolveSCTPAddr(SCTP6, address)
+		if err != nil {
+			return nil, err
+		}
+		addr.AddressFamily = SCTP6Only
+		return addr, nil
+	case "sctp":
+		addr, err := ResolveSCTPAddr(SCTP6, address)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range addr.IPAddrs {
+			if ip.IP.To4() == nil {
+				return addr, nil
+			}
+		}
+		addr.AddressFamily = SCTP4
+		return addr, nil
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+}
+
+// wildcardSCTPAddr returns the unspecified address of the given family.
+func wildcardSCTPAddr(family int) *SCTPAddr {
+	if family == syscall.AF_INET6 {
+		return &SCTPAddr{
+			IPAddrs:       []net.IPAddr{{IP: net.IPv6zero}},
+			AddressFamily: SCTP6,
+		}
+	}
+	return &SCTPAddr{
+		IPAddrs:       []net.IPAddr{{IP: net.IPv4zero}},
+		AddressFamily: SCTP4,
+	}
+}
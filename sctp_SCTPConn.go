@@ -1,7 +1,11 @@
 package sctp
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
+	"os"
 	"sync/atomic"
 	"time"
 
@@ -9,12 +13,55 @@ import (
 )
 
 type SCTPConn struct {
-	fd int32
+	fd *sctpFD
+}
+
+// MessageTooLongError is returned by writes of messages the kernel
+// rejects with EMSGSIZE, typically because fragmentation is disabled and
+// the message does not fit in one chunk.
+type MessageTooLongError struct {
+	Size int
+}
+
+func (e *MessageTooLongError) Error() string {
+	return fmt.Sprintf("sctp: message of %d bytes too long", e.Size)
+}
+
+func (e *MessageTooLongError) Unwrap() error { return syscall.EMSGSIZE }
+
+// msgSizeErr turns EMSGSIZE returned for a message of size bytes into a
+// *MessageTooLongError.
+func msgSizeErr(err error, size int) error {
+	if err == syscall.EMSGSIZE {
+		return &MessageTooLongError{Size: size}
+	}
+	return err
+}
+
+func newSCTPConn(fd int, nonblocking bool) (*SCTPConn, error) {
+	sfd, err := newSCTPFD(fd, nonblocking)
+	if err != nil {
+		return nil, err
+	}
+	return &SCTPConn{
+		fd: sfd,
+	}, nil
 }
 
 func NewSCTPConnection(af SCTPAddressFamily, options InitMsg, mode SCTPSocketMode, nonblocking bool) (*SCTPConn, error) {
+	return newSCTPConnection(af.ToSyscall(), af == SCTP6Only, options, nil, mode, nonblocking)
+}
 
-	fd, err := SCTPSocket(af.ToSyscall(), mode)
+// NewSCTPConnectionWithExtensions acts like NewSCTPConnection and selects
+// the extensions the socket advertises in INIT. A nil ext leaves the kernel
+// defaults in place.
+func NewSCTPConnectionWithExtensions(af SCTPAddressFamily, options InitMsg, ext *Extensions, mode SCTPSocketMode, nonblocking bool) (*SCTPConn, error) {
+	return newSCTPConnection(af.ToSyscall(), af == SCTP6Only, options, ext, mode, nonblocking)
+}
+
+func newSCTPConnection(family int, ipv6only bool, options InitMsg, ext *Extensions, mode SCTPSocketMode, nonblocking bool) (*SCTPConn, error) {
+
+	fd, err := SCTPSocket(family, mode)
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +73,7 @@ func NewSCTPConnection(af SCTPAddressFamily, options InitMsg, mode SCTPSocketMod
 		}
 	}(fd)
 
-	if err = SCTPSetDefaultSockopts(fd, af.ToSyscall(), af == SCTP6Only); err != nil {
+	if err = SCTPSetDefaultSockopts(fd, family, ipv6only); err != nil {
 		return nil, err
 	}
 
@@ -34,42 +81,174 @@ func NewSCTPConnection(af SCTPAddressFamily, options InitMsg, mode SCTPSocketMod
 		return nil, err
 	}
 
-	if err := syscall.SetNonblock(fd, nonblocking); err != nil {
-		return nil, err
+	if ext != nil {
+		if err = SCTPSetExtensions(fd, ext); err != nil {
+			return nil, err
+		}
 	}
 
-	return &SCTPConn{
-		fd: int32(fd),
-	}, nil
+	return newSCTPConn(fd, nonblocking)
 }
 
 func (c *SCTPConn) GetSocketMode() (SCTPSocketMode, error) {
-	return SCTPGetSocketMode(c.FD())
+	var mode SCTPSocketMode
+	err := c.fd.control(func(fd int) (err error) {
+		mode, err = SCTPGetSocketMode(fd)
+		return
+	})
+	return mode, err
 }
 
+// GetNonblocking reports whether operations on c return EAGAIN instead of
+// waiting for the socket to become ready.
 func (c *SCTPConn) GetNonblocking() (bool, error) {
-	return SCTPGetNonblocking(c.FD())
+	return c.fd.isNonblocking(), nil
 }
 
+// SetNonblocking selects whether operations on c return EAGAIN instead of
+// waiting for the socket to become ready. The underlying descriptor always
+// stays non-blocking so that it can be driven by the runtime poller.
 func (c *SCTPConn) SetNonblocking(val bool) error {
-	return SCTPSetNonblocking(c.FD(), val)
+	c.fd.setNonblocking(val)
+	return nil
 }
 
 func (c *SCTPConn) Listen() error {
-	return SCTPListen(c.FD())
+	return c.fd.control(func(fd int) error {
+		return SCTPListen(fd)
+	})
 }
 
 func (c *SCTPConn) Bind(laddr *SCTPAddr) error {
-	return SCTPBind(c.FD(), laddr, SCTP_BINDX_ADD_ADDR)
+	return c.fd.control(func(fd int) error {
+		return SCTPBind(fd, laddr, SCTP_BINDX_ADD_ADDR)
+	})
+}
+
+// AddLocalAddrs binds the socket to more local addresses, also once it is
+// listening or connected. The port of laddr must be 0 or the port already
+// bound. Established associations learn about the new addresses through
+// ASCONF if both ends support it.
+func (c *SCTPConn) AddLocalAddrs(laddr *SCTPAddr) error {
+	if laddr == nil || len(laddr.IPAddrs) == 0 {
+		return &net.AddrError{Err: "missing address"}
+	}
+	return c.fd.control(func(fd int) error {
+		return SCTPBind(fd, laddr, SCTP_BINDX_ADD_ADDR)
+	})
+}
+
+// RemoveLocalAddrs unbinds the socket from some of its local addresses.
+// The last address cannot be removed.
+func (c *SCTPConn) RemoveLocalAddrs(laddr *SCTPAddr) error {
+	if laddr == nil || len(laddr.IPAddrs) == 0 {
+		return &net.AddrError{Err: "missing address"}
+	}
+	return c.fd.control(func(fd int) error {
+		return SCTPBind(fd, laddr, SCTP_BINDX_REM_ADDR)
+	})
 }
 
+// Connect sets up an association to raddr. Unless the socket is
+// non-blocking, it waits for the association to be established, on a
+// OneToMany socket as well, for no longer than the write deadline.
 func (c *SCTPConn) Connect(raddr *SCTPAddr) error {
-	_, err := SCTPConnect(c.FD(), raddr)
+	ctx := context.Background()
+	if t := c.fd.wdeadline.deadline(); !t.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, t)
+		defer cancel()
+	}
+	_, err := c.ConnectAssocContext(ctx, raddr, !c.fd.isNonblocking())
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = os.ErrDeadlineExceeded
+	}
 	return err
 }
 
+// ConnectAssoc sets up an association to raddr and returns its ID, which
+// on a OneToMany socket tells it apart from the others in SndInfo,
+// RecvMsgInfo, PeelOff and the option getters. On a OneToMany socket the
+// association is brought up in the background; see ConnectAssocContext to
+// wait for it.
+func (c *SCTPConn) ConnectAssoc(raddr *SCTPAddr) (AssocID, error) {
+	return c.ConnectAssocContext(context.Background(), raddr, false)
+}
+
+// ConnectAssocContext acts like ConnectAssoc. If wait is true, it only
+// returns once the association is established, that is once SCTP_COMM_UP
+// would be announced, or ctx is done. Waiting does not consume any
+// notification.
+func (c *SCTPConn) ConnectAssocContext(ctx context.Context, raddr *SCTPAddr, wait bool) (AssocID, error) {
+	id, err := c.fd.connect(ctx, raddr)
+	if err == syscall.EINPROGRESS && wait {
+		err = nil
+	}
+	if err != nil || !wait {
+		return id, err
+	}
+	return id, c.waitEstablished(ctx, id)
+}
+
+// waitEstablished polls the state of the association until it is
+// established, and fails if it is found shutting down instead. An association whose setup fails is freed by the kernel,
+// after which SCTP_STATUS no longer knows its ID.
+func (c *SCTPConn) waitEstablished(ctx context.Context, id AssocID) error {
+	delay := time.Millisecond
+	for {
+		status, err := c.Status(id)
+		if err == syscall.EINVAL {
+			return fmt.Errorf("association %d could not be established", id)
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case status.State == SCTP_ESTABLISHED:
+			return nil
+		case status.State > SCTP_ESTABLISHED:
+			return fmt.Errorf("association %d is shutting down: %v", id, status.State)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		if delay < 100*time.Millisecond {
+			delay *= 2
+		}
+	}
+}
+
+// AssocNumber returns the number of associations of a OneToMany socket.
+func (c *SCTPConn) AssocNumber() (int, error) {
+	var n int
+	err := c.fd.control(func(fd int) (err error) {
+		n, err = SCTPGetAssocNumber(fd)
+		return
+	})
+	return n, err
+}
+
+// AssocIDs returns the IDs of the associations of a OneToMany socket.
+func (c *SCTPConn) AssocIDs() ([]AssocID, error) {
+	var ids []AssocID
+	err := c.fd.control(func(fd int) (err error) {
+		ids, err = SCTPGetAssocIDList(fd)
+		return
+	})
+	return ids, err
+}
+
+// FD returns the underlying socket descriptor, or -1 once c is closed.
+// Using it is not coordinated with Close; prefer SyscallConn.
 func (c *SCTPConn) FD() int {
-	return int(atomic.LoadInt32(&c.fd))
+	if c.fd.isClosing() {
+		return -1
+	}
+	return c.fd.sysfd
 }
 
 func (c *SCTPConn) Write(b []byte) (int, error) {
@@ -85,27 +264,728 @@ func (c *SCTPConn) Read(b []byte) (int, error) {
 }
 
 func (c *SCTPConn) SetEvents(flags int) error {
-	return SCTPSetEvents(c.FD(), flags)
+	return c.fd.control(func(fd int) error {
+		return SCTPSetEvents(fd, flags)
+	})
 }
 
 func (c *SCTPConn) GetEvents() (int, error) {
-	return SCTPGetEvents(c.FD())
+	var flags int
+	err := c.fd.control(func(fd int) (err error) {
+		flags, err = SCTPGetEvents(fd)
+		return
+	})
+	return flags, err
+}
+
+// Subscribe turns notifications of eventType on or off. On OneToMany
+// sockets assocID scopes the subscription to one association, or is one of
+// SCTP_FUTURE_ASSOC, SCTP_CURRENT_ASSOC and SCTP_ALL_ASSOC; it is ignored
+// on OneToOne sockets.
+func (c *SCTPConn) Subscribe(assocID AssocID, eventType SCTPNotificationType, on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetEvent(fd, assocID, eventType, on)
+	})
+}
+
+// Subscribed reports whether notifications of eventType are turned on.
+func (c *SCTPConn) Subscribed(assocID AssocID, eventType SCTPNotificationType) (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetEvent(fd, assocID, eventType)
+		return
+	})
+	return on, err
+}
+
+// SetRecvRcvInfo selects whether every received message carries an
+// SCTP_RCVINFO control message, returned as RecvMsgInfo.RcvInfo.
+func (c *SCTPConn) SetRecvRcvInfo(on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetRecvRcvInfo(fd, on)
+	})
+}
+
+func (c *SCTPConn) GetRecvRcvInfo() (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetRecvRcvInfo(fd)
+		return
+	})
+	return on, err
+}
+
+// SetRecvNxtInfo selects whether received messages carry an SCTP_NXTINFO
+// control message describing the next queued message, returned as
+// RecvMsgInfo.NxtInfo.
+func (c *SCTPConn) SetRecvNxtInfo(on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetRecvNxtInfo(fd, on)
+	})
+}
+
+func (c *SCTPConn) GetRecvNxtInfo() (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetRecvNxtInfo(fd)
+		return
+	})
+	return on, err
+}
+
+// SetPRSupported turns the negotiation of PR-SCTP on or off for the
+// associations set up afterwards.
+func (c *SCTPConn) SetPRSupported(assocID AssocID, on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetPRSupported(fd, assocID, on)
+	})
+}
+
+// GetPRSupported reports whether PR-SCTP is enabled or, once the
+// association is up, whether the peer supports it.
+func (c *SCTPConn) GetPRSupported(assocID AssocID) (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetPRSupported(fd, assocID)
+		return
+	})
+	return on, err
+}
+
+// SetDefaultPRInfo sets the PR-SCTP policy of messages sent without one.
+func (c *SCTPConn) SetDefaultPRInfo(assocID AssocID, info PRInfo) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetDefaultPRInfo(fd, assocID, info)
+	})
+}
+
+func (c *SCTPConn) GetDefaultPRInfo(assocID AssocID) (PRInfo, error) {
+	var info PRInfo
+	err := c.fd.control(func(fd int) (err error) {
+		info, err = SCTPGetDefaultPRInfo(fd, assocID)
+		return
+	})
+	return info, err
+}
+
+// PRAssocStatus returns the number of messages of the association
+// abandoned under policy, or under any policy for SCTP_PR_SCTP_ALL.
+func (c *SCTPConn) PRAssocStatus(assocID AssocID, policy PRPolicy) (*PRStatus, error) {
+	var status *PRStatus
+	err := c.fd.control(func(fd int) (err error) {
+		status, err = SCTPGetPRAssocStatus(fd, assocID, policy)
+		return
+	})
+	return status, err
+}
+
+// PRStreamStatus is like PRAssocStatus for one outgoing stream.
+func (c *SCTPConn) PRStreamStatus(assocID AssocID, stream uint16, policy PRPolicy) (*PRStatus, error) {
+	var status *PRStatus
+	err := c.fd.control(func(fd int) (err error) {
+		status, err = SCTPGetPRStreamStatus(fd, assocID, stream, policy)
+		return
+	})
+	return status, err
+}
+
+// SetReconfigSupported turns the negotiation of stream reconfiguration on
+// or off for the associations set up afterwards.
+func (c *SCTPConn) SetReconfigSupported(assocID AssocID, on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetReconfigSupported(fd, assocID, on)
+	})
+}
+
+func (c *SCTPConn) GetReconfigSupported(assocID AssocID) (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetReconfigSupported(fd, assocID)
+		return
+	})
+	return on, err
+}
+
+// SetEnableStreamReset selects the reconfiguration requests the socket may
+// send, as a combination of SCTP_ENABLE_RESET_STREAM_REQ,
+// SCTP_ENABLE_RESET_ASSOC_REQ and SCTP_ENABLE_CHANGE_ASSOC_REQ.
+func (c *SCTPConn) SetEnableStreamReset(assocID AssocID, flags int) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetEnableStreamReset(fd, assocID, flags)
+	})
+}
+
+func (c *SCTPConn) GetEnableStreamReset(assocID AssocID) (int, error) {
+	var flags int
+	err := c.fd.control(func(fd int) (err error) {
+		flags, err = SCTPGetEnableStreamReset(fd, assocID)
+		return
+	})
+	return flags, err
+}
+
+// ResetStreams resets the listed streams, or all of them if streams is
+// empty, in the directions selected by SCTP_STREAM_RESET_INCOMING and
+// SCTP_STREAM_RESET_OUTGOING. The outcome is reported with
+// SCTP_STREAM_RESET_EVENT.
+func (c *SCTPConn) ResetStreams(assocID AssocID, flags int, streams ...uint16) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPResetStreams(fd, assocID, flags, streams)
+	})
+}
+
+// ResetAssoc resets the TSNs of the association. The outcome is reported
+// with SCTP_ASSOC_RESET_EVENT.
+func (c *SCTPConn) ResetAssoc(assocID AssocID) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPResetAssoc(fd, assocID)
+	})
+}
+
+// AddStreams adds streams to the association in either direction. The
+// outcome is reported with SCTP_STREAM_CHANGE_EVENT.
+func (c *SCTPConn) AddStreams(assocID AssocID, in, out uint16) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPAddStreams(fd, assocID, in, out)
+	})
+}
+
+// SetAuthSupported turns the negotiation of SCTP-AUTH on or off for the
+// associations set up afterwards.
+func (c *SCTPConn) SetAuthSupported(assocID AssocID, on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetAuthSupported(fd, assocID, on)
+	})
+}
+
+func (c *SCTPConn) GetAuthSupported(assocID AssocID) (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetAuthSupported(fd, assocID)
+		return
+	})
+	return on, err
+}
+
+// AuthChunk requires chunks of the given type, such as SCTP_CID_DATA or
+// SCTP_CID_ASCONF, to be authenticated by the peer.
+func (c *SCTPConn) AuthChunk(chunk uint8) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPAuthChunk(fd, chunk)
+	})
+}
+
+// SetHMACIdent sets the HMAC algorithms offered to the peer, in order of
+// preference.
+func (c *SCTPConn) SetHMACIdent(idents ...uint16) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetHMACIdent(fd, idents)
+	})
+}
+
+func (c *SCTPConn) GetHMACIdent() ([]uint16, error) {
+	var idents []uint16
+	err := c.fd.control(func(fd int) (err error) {
+		idents, err = SCTPGetHMACIdent(fd)
+		return
+	})
+	return idents, err
+}
+
+// SetAuthKey adds or replaces a shared key. Both ends must install the
+// same key under the same number before it is made active.
+func (c *SCTPConn) SetAuthKey(assocID AssocID, keyNumber uint16, key []byte) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetAuthKey(fd, assocID, keyNumber, key)
+	})
+}
+
+// SetActiveKey selects the shared key used for outgoing chunks.
+func (c *SCTPConn) SetActiveKey(assocID AssocID, keyNumber uint16) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetActiveKey(fd, assocID, keyNumber)
+	})
+}
+
+func (c *SCTPConn) GetActiveKey(assocID AssocID) (uint16, error) {
+	var keyNumber uint16
+	err := c.fd.control(func(fd int) (err error) {
+		keyNumber, err = SCTPGetActiveKey(fd, assocID)
+		return
+	})
+	return keyNumber, err
+}
+
+// DeactivateKey retires a shared key. SCTP_AUTH_FREE_KEY is indicated once
+// it is no longer in use, after which it can be deleted.
+func (c *SCTPConn) DeactivateKey(assocID AssocID, keyNumber uint16) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPDeactivateKey(fd, assocID, keyNumber)
+	})
+}
+
+// DeleteKey removes a shared key that is not active.
+func (c *SCTPConn) DeleteKey(assocID AssocID, keyNumber uint16) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPDeleteKey(fd, assocID, keyNumber)
+	})
+}
+
+// PeerAuthChunks returns the chunk types the peer requires to be
+// authenticated.
+func (c *SCTPConn) PeerAuthChunks(assocID AssocID) ([]uint8, error) {
+	var chunks []uint8
+	err := c.fd.control(func(fd int) (err error) {
+		chunks, err = SCTPGetPeerAuthChunks(fd, assocID)
+		return
+	})
+	return chunks, err
+}
+
+// LocalAuthChunks returns the chunk types the local endpoint requires to
+// be authenticated.
+func (c *SCTPConn) LocalAuthChunks(assocID AssocID) ([]uint8, error) {
+	var chunks []uint8
+	err := c.fd.control(func(fd int) (err error) {
+		chunks, err = SCTPGetLocalAuthChunks(fd, assocID)
+		return
+	})
+	return chunks, err
+}
+
+// SetStreamScheduler selects how outgoing streams share the association.
+func (c *SCTPConn) SetStreamScheduler(assocID AssocID, scheduler SCTPStreamScheduler) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetStreamScheduler(fd, assocID, scheduler)
+	})
+}
+
+func (c *SCTPConn) GetStreamScheduler(assocID AssocID) (SCTPStreamScheduler, error) {
+	var scheduler SCTPStreamScheduler
+	err := c.fd.control(func(fd int) (err error) {
+		scheduler, err = SCTPGetStreamScheduler(fd, assocID)
+		return
+	})
+	return scheduler, err
+}
+
+// SetStreamSchedulerValue sets the priority or weight of an outgoing
+// stream, depending on the scheduler.
+func (c *SCTPConn) SetStreamSchedulerValue(assocID AssocID, stream, value uint16) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetStreamSchedulerValue(fd, assocID, stream, value)
+	})
+}
+
+func (c *SCTPConn) GetStreamSchedulerValue(assocID AssocID, stream uint16) (uint16, error) {
+	var value uint16
+	err := c.fd.control(func(fd int) (err error) {
+		value, err = SCTPGetStreamSchedulerValue(fd, assocID, stream)
+		return
+	})
+	return value, err
+}
+
+// SetInterleavingSupported turns the negotiation of I-DATA chunks on or
+// off for the associations set up afterwards. With interleaving, a large
+// message on one stream no longer holds up messages on the others.
+func (c *SCTPConn) SetInterleavingSupported(assocID AssocID, on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetInterleavingSupported(fd, assocID, on)
+	})
+}
+
+func (c *SCTPConn) GetInterleavingSupported(assocID AssocID) (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetInterleavingSupported(fd, assocID)
+		return
+	})
+	return on, err
+}
+
+// SetExtensions selects the extensions advertised for the associations
+// set up afterwards. A nil ext changes nothing.
+func (c *SCTPConn) SetExtensions(ext *Extensions) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetExtensions(fd, ext)
+	})
+}
+
+// NegotiatedExtensions reports the extensions both ends of the association
+// agreed on once it is up. PeerAdaptationLayer is filled in from the
+// SCTP_ADAPTATION_INDICATION notification, provided notifications are
+// enabled with that event subscribed and it has been read.
+func (c *SCTPConn) NegotiatedExtensions(assocID AssocID) (*Extensions, error) {
+	mode, err := c.GetSocketMode()
+	if err != nil {
+		return nil, err
+	}
+	var ext *Extensions
+	err = c.fd.control(func(fd int) (err error) {
+		ext, err = SCTPGetExtensions(fd, assocID)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ntf := c.fd.notifications(); ntf != nil {
+		if ind, ok := ntf.peerAdaptation(assocID, mode == OneToOne); ok {
+			ext.PeerAdaptationLayer = &ind
+		}
+	}
+	return ext, nil
+}
+
+// SetNoDelay controls whether small messages are sent right away rather
+// than held back for bundling.
+func (c *SCTPConn) SetNoDelay(noDelay bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetNoDelay(fd, noDelay)
+	})
+}
+
+func (c *SCTPConn) GetNoDelay() (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetNoDelay(fd)
+		return
+	})
+	return on, err
+}
+
+// SetMaxSeg sets the largest DATA chunk payload sent on the association.
+// Zero lets the kernel follow the path MTU.
+func (c *SCTPConn) SetMaxSeg(assocID AssocID, size uint32) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetMaxSeg(fd, assocID, size)
+	})
+}
+
+func (c *SCTPConn) GetMaxSeg(assocID AssocID) (uint32, error) {
+	var size uint32
+	err := c.fd.control(func(fd int) (err error) {
+		size, err = SCTPGetMaxSeg(fd, assocID)
+		return
+	})
+	return size, err
+}
+
+// SetDisableFragments stops the kernel from fragmenting messages. Writes
+// of messages that do not fit in one chunk then fail with a
+// *MessageTooLongError.
+func (c *SCTPConn) SetDisableFragments(on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetDisableFragments(fd, on)
+	})
+}
+
+func (c *SCTPConn) GetDisableFragments() (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetDisableFragments(fd)
+		return
+	})
+	return on, err
+}
+
+// SetFragmentInterleave sets how the parts of partially delivered messages
+// may be interleaved on reads: 0 not at all, 1 across associations, 2
+// across associations and streams.
+func (c *SCTPConn) SetFragmentInterleave(level int) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetFragmentInterleave(fd, level)
+	})
+}
+
+func (c *SCTPConn) GetFragmentInterleave() (int, error) {
+	var level int
+	err := c.fd.control(func(fd int) (err error) {
+		level, err = SCTPGetFragmentInterleave(fd)
+		return
+	})
+	return level, err
+}
+
+// SetPartialDeliveryPoint sets the size of the received part of a message
+// from which the kernel starts handing it over, without MSG_EOR, before
+// the rest has arrived.
+func (c *SCTPConn) SetPartialDeliveryPoint(size uint32) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetPartialDeliveryPoint(fd, size)
+	})
+}
+
+func (c *SCTPConn) GetPartialDeliveryPoint() (uint32, error) {
+	var size uint32
+	err := c.fd.control(func(fd int) (err error) {
+		size, err = SCTPGetPartialDeliveryPoint(fd)
+		return
+	})
+	return size, err
+}
+
+// SetAutoASCONF turns the automatic announcement of local address changes
+// on or off. It only applies to sockets bound to the wildcard address.
+func (c *SCTPConn) SetAutoASCONF(on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetAutoASCONF(fd, on)
+	})
+}
+
+func (c *SCTPConn) GetAutoASCONF() (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetAutoASCONF(fd)
+		return
+	})
+	return on, err
+}
+
+// SetASCONFSupported turns the negotiation of dynamic address
+// reconfiguration on or off for the associations set up afterwards.
+func (c *SCTPConn) SetASCONFSupported(assocID AssocID, on bool) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetASCONFSupported(fd, assocID, on)
+	})
+}
+
+func (c *SCTPConn) GetASCONFSupported(assocID AssocID) (bool, error) {
+	var on bool
+	err := c.fd.control(func(fd int) (err error) {
+		on, err = SCTPGetASCONFSupported(fd, assocID)
+		return
+	})
+	return on, err
+}
+
+// SetPeerPrimaryAddr asks the peer to send to laddr, one of the local
+// addresses of the association, by default.
+func (c *SCTPConn) SetPeerPrimaryAddr(assocID AssocID, laddr *SCTPAddr) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetPeerPrimaryAddr(fd, assocID, laddr)
+	})
 }
 
 func (c *SCTPConn) SetDefaultSentParam(info *SndRcvInfo) error {
-	return SCTPSetDefaultSentParam(c.FD(), info)
+	return c.fd.control(func(fd int) error {
+		return SCTPSetDefaultSentParam(fd, info)
+	})
 }
 
 func (c *SCTPConn) GetDefaultSentParam() (*SndRcvInfo, error) {
-	return SCTPGetDefaultSentParam(c.FD())
+	var info *SndRcvInfo
+	err := c.fd.control(func(fd int) (err error) {
+		info, err = SCTPGetDefaultSentParam(fd)
+		return
+	})
+	return info, err
 }
 
 func (c *SCTPConn) SCTPGetPrimaryPeerAddr() (*SCTPAddr, error) {
-	return SCTPGetAddrs(c.FD(), 0, SCTP_PRIMARY_ADDR)
+	return c.PrimaryPeerAddr(0)
+}
+
+// PrimaryPeerAddr returns the peer address the association sends to by
+// default.
+func (c *SCTPConn) PrimaryPeerAddr(assocID AssocID) (*SCTPAddr, error) {
+	var addr *SCTPAddr
+	err := c.fd.control(func(fd int) (err error) {
+		addr, err = SCTPGetPrimaryAddr(fd, assocID)
+		return
+	})
+	return addr, err
+}
+
+// SetPrimaryPeerAddr makes raddr, one of the peer addresses of the
+// association, the one sent to by default.
+func (c *SCTPConn) SetPrimaryPeerAddr(assocID AssocID, raddr *SCTPAddr) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetPrimaryAddr(fd, assocID, raddr)
+	})
+}
+
+// PeerPaths returns the state of the path to each peer address of the
+// association.
+func (c *SCTPConn) PeerPaths(assocID AssocID) ([]PeerAddrInfo, error) {
+	var paths []PeerAddrInfo
+	err := c.fd.control(func(fd int) error {
+		raddr, err := SCTPGetAddrs(fd, assocID, SCTP_GET_PEER_ADDRS)
+		if err != nil {
+			return err
+		}
+		for _, ip := range raddr.IPAddrs {
+			addr := &SCTPAddr{IPAddrs: []net.IPAddr{ip}, Port: raddr.Port}
+			info, err := SCTPGetPeerAddrInfo(fd, assocID, addr)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, *info)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// PeerAddrParams returns the heartbeat, retransmission, PMTU and marking
+// parameters of the path to raddr or, if raddr is nil, the defaults of the
+// association.
+func (c *SCTPConn) PeerAddrParams(assocID AssocID, raddr *SCTPAddr) (*PeerAddrParams, error) {
+	var params *PeerAddrParams
+	err := c.fd.control(func(fd int) (err error) {
+		params, err = SCTPGetPeerAddrParams(fd, assocID, raddr)
+		return
+	})
+	return params, err
+}
+
+// SetPeerAddrParams applies p to the path to p.Addr or, if p.Addr is nil,
+// to every path of the association. Zero values leave the corresponding
+// parameter unchanged; features are switched with the SPP_* flags, e.g.
+// SPP_HB_DISABLE turns heartbeats off and SPP_PMTUD_DISABLE together with
+// PathMTU fixes the path MTU.
+func (c *SCTPConn) SetPeerAddrParams(assocID AssocID, p *PeerAddrParams) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetPeerAddrParams(fd, assocID, p)
+	})
+}
+
+// RequestHeartbeat sends a heartbeat to raddr right away.
+func (c *SCTPConn) RequestHeartbeat(assocID AssocID, raddr *SCTPAddr) error {
+	if raddr == nil || len(raddr.IPAddrs) == 0 {
+		return &net.AddrError{Err: "missing address"}
+	}
+	return c.SetPeerAddrParams(assocID, &PeerAddrParams{
+		Addr:  raddr,
+		Flags: SPP_HB_DEMAND,
+	})
+}
+
+// PeerAddrThresholds returns the failure thresholds of the path to raddr
+// or, if raddr is nil, the defaults of the association.
+func (c *SCTPConn) PeerAddrThresholds(assocID AssocID, raddr *SCTPAddr) (*PeerAddrThresholds, error) {
+	var t *PeerAddrThresholds
+	err := c.fd.control(func(fd int) (err error) {
+		t, err = SCTPGetPeerAddrThresholds(fd, assocID, raddr)
+		return
+	})
+	return t, err
+}
+
+// SetPeerAddrThresholds sets the failure thresholds of the path to t.Addr
+// or, if t.Addr is nil, of every path of the association. A PFThreshold
+// below PathMaxRxt enables the potentially-failed state of RFC 7829.
+func (c *SCTPConn) SetPeerAddrThresholds(assocID AssocID, t *PeerAddrThresholds) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetPeerAddrThresholds(fd, assocID, t)
+	})
+}
+
+// GetRTOInfo returns the retransmission timeout bounds of the association.
+func (c *SCTPConn) GetRTOInfo(assocID AssocID) (*RTOInfo, error) {
+	var info *RTOInfo
+	err := c.fd.control(func(fd int) (err error) {
+		info, err = SCTPGetRTOInfo(fd, assocID)
+		return
+	})
+	return info, err
+}
+
+// SetRTOInfo sets the retransmission timeout bounds of the association.
+// Zero fields are left unchanged.
+func (c *SCTPConn) SetRTOInfo(assocID AssocID, info *RTOInfo) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetRTOInfo(fd, assocID, info)
+	})
+}
+
+// GetAssocParams returns the parameters of the association.
+func (c *SCTPConn) GetAssocParams(assocID AssocID) (*AssocParams, error) {
+	var p *AssocParams
+	err := c.fd.control(func(fd int) (err error) {
+		p, err = SCTPGetAssocParams(fd, assocID)
+		return
+	})
+	return p, err
+}
+
+// SetAssocParams sets the maximum number of retransmissions and the cookie
+// lifetime of the association. Zero fields are left unchanged.
+func (c *SCTPConn) SetAssocParams(assocID AssocID, p *AssocParams) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetAssocParams(fd, assocID, p)
+	})
+}
+
+// GetSackInfo returns the delayed SACK settings of the association.
+func (c *SCTPConn) GetSackInfo(assocID AssocID) (*SackInfo, error) {
+	var info *SackInfo
+	err := c.fd.control(func(fd int) (err error) {
+		info, err = SCTPGetSackInfo(fd, assocID)
+		return
+	})
+	return info, err
+}
+
+// SetSackInfo sets the delayed SACK settings of the association.
+func (c *SCTPConn) SetSackInfo(assocID AssocID, info *SackInfo) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetSackInfo(fd, assocID, info)
+	})
+}
+
+// GetMaxBurst returns the maximum number of packets sent at once on the
+// association, zero meaning no limit.
+func (c *SCTPConn) GetMaxBurst(assocID AssocID) (uint32, error) {
+	var burst uint32
+	err := c.fd.control(func(fd int) (err error) {
+		burst, err = SCTPGetMaxBurst(fd, assocID)
+		return
+	})
+	return burst, err
+}
+
+// SetMaxBurst limits the number of packets sent at once on the
+// association. Zero disables the limit.
+func (c *SCTPConn) SetMaxBurst(assocID AssocID, burst uint32) error {
+	return c.fd.control(func(fd int) error {
+		return SCTPSetMaxBurst(fd, assocID, burst)
+	})
+}
+
+// Status returns a snapshot of the state, windows, queues, stream counts
+// and primary path of the association.
+func (c *SCTPConn) Status(assocID AssocID) (*AssocStatus, error) {
+	var status *AssocStatus
+	err := c.fd.control(func(fd int) (err error) {
+		status, err = SCTPGetStatus(fd, assocID)
+		return
+	})
+	return status, err
+}
+
+// Stats returns the retransmission, SACK and chunk counters of the
+// association. Reading them resets the observed maximum RTO.
+func (c *SCTPConn) Stats(assocID AssocID) (*AssocStats, error) {
+	var stats *AssocStats
+	err := c.fd.control(func(fd int) (err error) {
+		stats, err = SCTPGetAssocStats(fd, assocID)
+		return
+	})
+	return stats, err
+}
+
+func (c *SCTPConn) getAddrs(id AssocID, optname int) (*SCTPAddr, error) {
+	var addr *SCTPAddr
+	err := c.fd.control(func(fd int) (err error) {
+		addr, err = SCTPGetAddrs(fd, id, optname)
+		return
+	})
+	return addr, err
 }
 
-func (c *SCTPConn) SCTPLocalAddr(id uint16) (*SCTPAddr, error) {
-	return SCTPGetLocalAddr(c.FD(), id)
+func (c *SCTPConn) SCTPLocalAddr(id AssocID) (*SCTPAddr, error) {
+	return c.getAddrs(id, SCTP_GET_LOCAL_ADDRS)
 }
 
 func (c *SCTPConn) LocalAddr() net.Addr {
@@ -116,8 +996,8 @@ func (c *SCTPConn) LocalAddr() net.Addr {
 	return addr
 }
 
-func (c *SCTPConn) SCTPRemoteAddr(id uint16) (*SCTPAddr, error) {
-	return SCTPGetRemoteAddr(c.FD(), id)
+func (c *SCTPConn) SCTPRemoteAddr(id AssocID) (*SCTPAddr, error) {
+	return c.getAddrs(id, SCTP_GET_PEER_ADDRS)
 }
 
 func (c *SCTPConn) RemoteAddr() net.Addr {
@@ -128,49 +1008,205 @@ func (c *SCTPConn) RemoteAddr() net.Addr {
 	return addr
 }
 
-func (c *SCTPConn) PeelOff(id int32) (*SCTPConn, error) {
-	fd, err := SCTPPeelOff(c.FD(), id)
+func (c *SCTPConn) PeelOff(id AssocID) (*SCTPConn, error) {
+	var fd int
+	err := c.fd.control(func(s int) (err error) {
+		fd, err = SCTPPeelOff(s, id)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	conn := &SCTPConn{
-		fd: int32(fd),
-	}
-
-	blocking, err := c.GetNonblocking()
-	if err != nil {
-		return nil, err
-	}
-
-	if err := conn.SetNonblocking(blocking); err != nil {
-		return nil, err
-	}
-
-	return conn, nil
-
+	return newSCTPConn(fd, c.fd.isNonblocking())
 }
 
+// SetDeadline sets the read and write deadlines associated with c.
+// Operations that exceed the deadline fail with an error whose Timeout
+// method reports true.
 func (c *SCTPConn) SetDeadline(t time.Time) error {
-	return syscall.EOPNOTSUPP
+	return c.fd.setDeadline(t)
 }
 
 func (c *SCTPConn) SetReadDeadline(t time.Time) error {
-	return syscall.EOPNOTSUPP
+	return c.fd.setReadDeadline(t)
 }
 
 func (c *SCTPConn) SetWriteDeadline(t time.Time) error {
-	return syscall.EOPNOTSUPP
+	return c.fd.setWriteDeadline(t)
 }
 
 func (c *SCTPConn) SCTPWrite(b []byte, info *SndRcvInfo) (int, error) {
-	return SCTPWrite(c.FD(), b, info)
+	return c.WriteMsgContext(context.Background(), b, info)
+}
+
+// WriteMsgContext acts like SCTPWrite but gives up waiting for the socket
+// to become writable once ctx is done, returning ctx.Err(). The association
+// is left intact.
+func (c *SCTPConn) WriteMsgContext(ctx context.Context, b []byte, info *SndRcvInfo) (int, error) {
+	var n int
+	err := c.fd.write(ctx, func(s int) error {
+		var err error
+		n, err = SCTPWrite(s, b, info)
+		return msgSizeErr(err, len(b))
+	})
+	return n, err
 }
 
 func (c *SCTPConn) SCTPRead(b []byte) (int, *OOBMessage, int, error) {
-	return SCTPRead(c.FD(), b)
+	return c.ReadMsgContext(context.Background(), b)
+}
+
+// ReadMsgContext acts like SCTPRead but gives up waiting for a message once
+// ctx is done, returning ctx.Err(). The association is left intact.
+func (c *SCTPConn) ReadMsgContext(ctx context.Context, b []byte) (int, *OOBMessage, int, error) {
+	var oob *OOBMessage
+	n, flags, err := c.readMsg(ctx, b, func(s int) (n, flags int, err error) {
+		n, oob, flags, err = SCTPRead(s, b)
+		return
+	})
+	return n, oob, flags, err
+}
+
+// SendMsg sends b as one message described by info, which may be nil.
+func (c *SCTPConn) SendMsg(b []byte, info *SndInfo) (int, error) {
+	var n int
+	err := c.fd.write(context.Background(), func(s int) error {
+		var err error
+		n, err = SCTPSendMsg(s, b, info)
+		return msgSizeErr(err, len(b))
+	})
+	return n, err
+}
+
+// SendMsgPR acts like SendMsg, sending b under the PR-SCTP policy pr
+// rather than the default one.
+func (c *SCTPConn) SendMsgPR(b []byte, info *SndInfo, pr *PRInfo) (int, error) {
+	var n int
+	err := c.fd.write(context.Background(), func(s int) error {
+		var err error
+		n, err = SCTPSendMsgPR(s, b, info, pr)
+		return msgSizeErr(err, len(b))
+	})
+	return n, err
+}
+
+// RecvMsg reads a message, or part of one, into b and returns the
+// ancillary data that came with it. MSG_EOR is set in the returned flags
+// once the end of the message has been read.
+func (c *SCTPConn) RecvMsg(b []byte) (int, *RecvMsgInfo, int, error) {
+	var info *RecvMsgInfo
+	n, flags, err := c.readMsg(context.Background(), b, func(s int) (n, flags int, err error) {
+		n, info, flags, err = SCTPRecvMsg(s, b)
+		return
+	})
+	return n, info, flags, err
+}
+
+// ReadMsgSCTP acts like RecvMsg and also returns the peer address the
+// message came from, which on a OneToMany socket tells the associations
+// apart alongside the association ID.
+func (c *SCTPConn) ReadMsgSCTP(b []byte) (int, *RecvMsgInfo, int, *SCTPAddr, error) {
+	var (
+		info *RecvMsgInfo
+		from *SCTPAddr
+	)
+	n, flags, err := c.readMsg(context.Background(), b, func(s int) (n, flags int, err error) {
+		n, info, flags, from, err = SCTPRecvMsgFrom(s, b)
+		return
+	})
+	return n, info, flags, from, err
+}
+
+// WriteMsgSCTP sends b as one message to raddr, described by info, which
+// may be nil. On a OneToMany socket without an association to raddr, one
+// is set up and b is sent along with the COOKIE-ECHO.
+func (c *SCTPConn) WriteMsgSCTP(b []byte, info *SndInfo, raddr *SCTPAddr) (int, error) {
+	var n int
+	err := c.fd.write(context.Background(), func(s int) error {
+		var err error
+		n, err = SCTPSendMsgTo(s, b, info, raddr)
+		return msgSizeErr(err, len(b))
+	})
+	return n, err
+}
+
+// readMsg reads into b with recv. Once notifications are enabled, those
+// read are passed on to the notification channel instead of being
+// returned, and readMsg carries on until data arrives.
+func (c *SCTPConn) readMsg(ctx context.Context, b []byte, recv func(s int) (n, flags int, err error)) (n, flags int, err error) {
+	read := func(s int) (err error) {
+		n, flags, err = recv(s)
+		return
+	}
+	ntf := c.fd.notifications()
+	if ntf == nil || len(b) == 0 {
+		err = c.fd.read(ctx, read)
+		return
+	}
+
+	// notifications that did not fit in the channel are handed over
+	// before reading more
+	if err = ntf.deliver(ctx, c.fd.rdeadline.deadline(), nil); err != nil {
+		return 0, 0, err
+	}
+	var partial []byte
+	for {
+		if err = c.fd.read(ctx, read); err != nil || flags&MSG_NOTIFICATION == 0 {
+			return
+		}
+		partial = append(partial, b[:n]...)
+		if flags&MSG_EOR == 0 {
+			continue
+		}
+		notif, perr := SCTPParseNotification(partial)
+		partial = nil
+		if perr != nil {
+			atomic.AddUint64(&ntf.dropped, 1)
+			continue
+		}
+		ntf.track(notif)
+		if err = ntf.deliver(ctx, c.fd.rdeadline.deadline(), notif); err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// EnableNotifications diverts the notifications read from the socket to
+// the channel returned by Notifications, which holds up to size of them.
+// From then on, reads only return data. Notifications are picked up while
+// the socket is being read, so a reader must be running for them to
+// arrive. Which notifications are sent is chosen with SetEvents or
+// Subscribe.
+//
+// When the consumer falls behind and the channel is full, overflow decides
+// whether reads wait for it or the notification is dropped. The channel is
+// closed when the socket is closed.
+func (c *SCTPConn) EnableNotifications(size int, overflow NotificationOverflow) error {
+	return c.fd.enableNotifications(size, overflow)
+}
+
+// Notifications returns the channel set up by EnableNotifications, or nil
+// if notifications have not been enabled.
+func (c *SCTPConn) Notifications() <-chan *Notification {
+	if ntf := c.fd.notifications(); ntf != nil {
+		return ntf.ch
+	}
+	return nil
+}
+
+// DroppedNotifications returns the number of notifications discarded
+// because the channel was full under NotificationDrop, or because they
+// could not be decoded.
+func (c *SCTPConn) DroppedNotifications() uint64 {
+	if ntf := c.fd.notifications(); ntf != nil {
+		return atomic.LoadUint64(&ntf.dropped)
+	}
+	return 0
 }
 
+// Close shuts down the association and closes the socket. Blocked reads
+// and writes are unblocked and fail with net.ErrClosed, as does any call to
+// Close after the first.
 func (c *SCTPConn) Close() error {
-	return SCTPClose(c.FD())
+	return c.fd.close()
 }
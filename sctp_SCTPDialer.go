@@ -0,0 +1,155 @@
+package sctp
+
+import (
+	"context"
+	"net"
+	stdsyscall "syscall"
+	"time"
+)
+
+// SCTPDialer contains options for setting up an SCTP association.
+// The zero value is a OneToOne dialer without timeout.
+type SCTPDialer struct {
+	// InitMsg is applied to the socket before the association is set up.
+	InitMsg InitMsg
+
+	// Extensions, if not nil, selects the optional extensions advertised
+	// in INIT. If nil, the kernel defaults apply.
+	Extensions *Extensions
+
+	// SocketMode selects between OneToOne and OneToMany sockets.
+	SocketMode SCTPSocketMode
+
+	// LocalAddr is the local address to bind to before connecting.
+	// If nil, the kernel picks the local addresses.
+	LocalAddr *SCTPAddr
+
+	// Timeout is the maximum amount of time a dial will wait for the
+	// association to come up. Zero means no timeout.
+	Timeout time.Duration
+
+	// Deadline is the absolute point in time after which the dial fails.
+	// If Timeout is also set, the earlier of the two is used.
+	Deadline time.Time
+
+	// HeartbeatInterval is the interval between heartbeats sent on each
+	// path of the association. Zero keeps the kernel default, a negative
+	// value disables heartbeats.
+	HeartbeatInterval time.Duration
+
+	// RTOInfo, AssocParams and SackInfo, if not nil, are applied to the
+	// socket before the association is set up.
+	RTOInfo     *RTOInfo
+	AssocParams *AssocParams
+	SackInfo    *SackInfo
+
+	// MaxBurst limits the number of packets sent at once. Zero keeps the
+	// kernel default, a negative value disables the limit.
+	MaxBurst int
+
+	// Control, if not nil, is called after the socket is created and
+	// before it is bound or connected.
+	Control func(network, address string, c stdsyscall.RawConn) error
+}
+
+func (d *SCTPDialer) deadline(ctx context.Context, now time.Time) (earliest time.Time) {
+	if d.Timeout != 0 {
+		earliest = now.Add(d.Timeout)
+	}
+	if t, ok := ctx.Deadline(); ok && (earliest.IsZero() || t.Before(earliest)) {
+		earliest = t
+	}
+	if !d.Deadline.IsZero() && (earliest.IsZero() || d.Deadline.Before(earliest)) {
+		earliest = d.Deadline
+	}
+	return earliest
+}
+
+// Dial connects to the address on the named network, which must be
+// "sctp", "sctp4" or "sctp6".
+func (d *SCTPDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using the
+// provided context.
+func (d *SCTPDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	raddr, err := resolveSCTPNetworkAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	c, err := d.DialSCTPContext(ctx, network, raddr)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DialSCTPContext acts like DialContext but takes a resolved address and
+// returns an *SCTPConn.
+func (d *SCTPDialer) DialSCTPContext(ctx context.Context, network string, raddr *SCTPAddr) (*SCTPConn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	if raddr == nil {
+		return nil, &net.AddrError{Err: "missing address"}
+	}
+
+	family, ipv6only, err := sctpNetworkFamily(network, d.LocalAddr, raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline := d.deadline(ctx, time.Now()); !deadline.IsZero() {
+		if t, ok := ctx.Deadline(); !ok || deadline.Before(t) {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+	}
+
+	c, err := newSCTPConnection(family, ipv6only, d.InitMsg, d.Extensions, d.SocketMode, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.setup(ctx, network, c, raddr); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (d *SCTPDialer) setup(ctx context.Context, network string, c *SCTPConn, raddr *SCTPAddr) error {
+	err := c.fd.control(func(fd int) error {
+		if err := setHeartbeatInterval(fd, d.HeartbeatInterval); err != nil {
+			return err
+		}
+		return setAssocTuning(fd, d.RTOInfo, d.AssocParams, d.SackInfo, d.MaxBurst)
+	})
+	if err != nil {
+		return err
+	}
+	if d.Control != nil {
+		if err := d.Control(network, raddr.String(), &rawConn{fd: c.fd}); err != nil {
+			return err
+		}
+	}
+	if d.LocalAddr != nil {
+		if err := c.Bind(d.LocalAddr); err != nil {
+			return err
+		}
+	}
+	_, err = c.ConnectAssocContext(ctx, raddr, true)
+	return err
+}
+
+// DialSCTP acts like a dial for SCTP networks. The network must be "sctp",
+// "sctp4" or "sctp6". If laddr is not nil, it is bound as the local
+// address of the association.
+func DialSCTP(network string, laddr, raddr *SCTPAddr) (*SCTPConn, error) {
+	d := SCTPDialer{
+		LocalAddr: laddr,
+	}
+	return d.DialSCTPContext(context.Background(), network, raddr)
+}
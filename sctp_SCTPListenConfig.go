@@ -0,0 +1,123 @@
+package sctp
+
+import (
+	"context"
+	"net"
+	stdsyscall "syscall"
+	"time"
+)
+
+// SCTPListenConfig contains options for listening to an address.
+// The zero value listens on a OneToOne socket.
+type SCTPListenConfig struct {
+	// InitMsg is applied to the listening socket and inherited by the
+	// associations accepted on it.
+	InitMsg InitMsg
+
+	// Extensions, if not nil, selects the optional extensions advertised
+	// in INIT-ACK. If nil, the kernel defaults apply.
+	Extensions *Extensions
+
+	// SocketMode selects between OneToOne and OneToMany sockets.
+	SocketMode SCTPSocketMode
+
+	// HeartbeatInterval is the interval between heartbeats sent on each
+	// path of accepted associations. Zero keeps the kernel default, a
+	// negative value disables heartbeats.
+	HeartbeatInterval time.Duration
+
+	// RTOInfo, AssocParams and SackInfo, if not nil, are applied to the
+	// listening socket and inherited by the associations accepted on it.
+	RTOInfo     *RTOInfo
+	AssocParams *AssocParams
+	SackInfo    *SackInfo
+
+	// MaxBurst limits the number of packets sent at once. Zero keeps the
+	// kernel default, a negative value disables the limit.
+	MaxBurst int
+
+	// AutoPeelOff makes Accept on a OneToMany listener peel off each new
+	// association and return it as a OneToOne SCTPConn. The listening
+	// socket must then not be read other than through Accept. It is
+	// ignored for OneToOne listeners.
+	AutoPeelOff bool
+
+	// Control, if not nil, is called after the socket is created and
+	// before it is bound.
+	Control func(network, address string, c stdsyscall.RawConn) error
+}
+
+// Listen announces on the local network address, which must be one of
+// "sctp", "sctp4" or "sctp6".
+func (lc *SCTPListenConfig) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	laddr, err := resolveSCTPNetworkAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := lc.ListenSCTP(ctx, network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	return ln, nil
+}
+
+// ListenSCTP acts like Listen but takes a resolved address and returns an
+// *SCTPListener. If laddr is nil, the listener is bound to the wildcard
+// address and an ephemeral port.
+func (lc *SCTPListenConfig) ListenSCTP(ctx context.Context, network string, laddr *SCTPAddr) (*SCTPListener, error) {
+	family, ipv6only, err := sctpNetworkFamily(network, laddr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if laddr == nil {
+		laddr = wildcardSCTPAddr(family)
+	}
+	return lc.listen(network, family, ipv6only, laddr, false)
+}
+
+func (lc *SCTPListenConfig) listen(network string, family int, ipv6only bool, laddr *SCTPAddr, nonblocking bool) (*SCTPListener, error) {
+	conn, err := newSCTPConnection(family, ipv6only, lc.InitMsg, lc.Extensions, lc.SocketMode, nonblocking)
+	if err != nil {
+		return nil, err
+	}
+	ln := &SCTPListener{SCTPConn: *conn, socketMode: lc.SocketMode}
+
+	if err := lc.setup(network, ln, laddr); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func (lc *SCTPListenConfig) setup(network string, ln *SCTPListener, laddr *SCTPAddr) error {
+	err := ln.fd.control(func(fd int) error {
+		if err := setHeartbeatInterval(fd, lc.HeartbeatInterval); err != nil {
+			return err
+		}
+		return setAssocTuning(fd, lc.RTOInfo, lc.AssocParams, lc.SackInfo, lc.MaxBurst)
+	})
+	if err != nil {
+		return err
+	}
+	if lc.Control != nil {
+		if err := lc.Control(network, laddr.String(), &rawConn{fd: ln.fd}); err != nil {
+			return err
+		}
+	}
+	if err := ln.Bind(laddr); err != nil {
+		return err
+	}
+	if lc.AutoPeelOff && lc.SocketMode == OneToMany {
+		if err := ln.enableAutoPeelOff(); err != nil {
+			return err
+		}
+	}
+	return ln.Listen()
+}
+
+// ListenSCTP acts like Listen for SCTP networks with a OneToOne socket.
+// The network must be "sctp", "sctp4" or "sctp6".
+func ListenSCTP(network string, laddr *SCTPAddr) (*SCTPListener, error) {
+	var lc SCTPListenConfig
+	return lc.ListenSCTP(context.Background(), network, laddr)
+}
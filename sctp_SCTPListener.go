@@ -1,60 +1,288 @@
 package sctp
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"sync/atomic"
+	"time"
+
+	syscall "golang.org/x/sys/unix"
 )
 
 type SCTPListener struct {
 	SCTPConn
 	socketMode SCTPSocketMode
+	peelOff    *autoPeelOff
+
+	// managed is set once an AssociationManager reads the socket
+	managed int32
+}
+
+// autoPeelOff is the state of a OneToMany listener handing out its
+// associations from Accept. It records the settings changed on the
+// listening socket, which are put back on the peeled-off sockets.
+type autoPeelOff struct {
+	// sem serializes accepts, so that one message is looked at by a
+	// single accept at a time
+	sem chan struct{}
+	// notif has been read from the socket but not passed on yet, as
+	// the accept reading it was given up; guarded by sem
+	notif *Notification
+
+	assocChange bool
+	rcvInfo     bool
 }
 
 func NewSCTPListener(laddr *SCTPAddr, init InitMsg, mode SCTPSocketMode, nonblocking bool) (*SCTPListener, error) {
+	return NewSCTPListenerWithExtensions(laddr, init, nil, mode, nonblocking)
+}
+
+// NewSCTPListenerWithExtensions acts like NewSCTPListener and selects the
+// extensions the socket advertises in INIT ACK. A nil ext leaves the kernel
+// defaults in place.
+func NewSCTPListenerWithExtensions(laddr *SCTPAddr, init InitMsg, ext *Extensions, mode SCTPSocketMode, nonblocking bool) (*SCTPListener, error) {
 	if laddr == nil {
 		return nil, fmt.Errorf("Local SCTPAddr is required")
 	}
 
-	conn, err := NewSCTPConnection(laddr.AddressFamily, init, mode, nonblocking)
+	lc := SCTPListenConfig{
+		InitMsg:    init,
+		SocketMode: mode,
+		Extensions: ext,
+	}
+	return lc.listen("sctp", laddr.AddressFamily.ToSyscall(), laddr.AddressFamily == SCTP6Only, laddr, nonblocking)
+}
+
+// AcceptSCTP waits for and returns the next SCTP connection to the listener.
+// OneToMany listeners only accept when set up with AutoPeelOff.
+func (ln *SCTPListener) AcceptSCTP() (*SCTPConn, error) {
+	return ln.AcceptContext(context.Background())
+}
+
+// AcceptContext acts like AcceptSCTP but gives up waiting once ctx is done,
+// returning ctx.Err().
+func (ln *SCTPListener) AcceptContext(ctx context.Context) (*SCTPConn, error) {
+	if ln.socketMode == OneToMany {
+		if ln.peelOff != nil {
+			return ln.acceptPeelOff(ctx)
+		}
+		return nil, fmt.Errorf("Calling Accept on OneToMany socket is invalid")
+	}
+
+	var fd int
+	err := ln.fd.read(ctx, func(s int) error {
+		var err error
+		fd, err = SCTPAccept(s)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	ln := &SCTPListener{SCTPConn: *conn, socketMode: mode}
-	ln.socketMode = mode
+	return newSCTPConn(fd, ln.fd.isNonblocking())
+}
 
-	if err := ln.Bind(laddr); err != nil {
-		return nil, err
+// enableAutoPeelOff makes Accept hand out the associations of a OneToMany
+// listener. SCTP_ASSOC_CHANGE notifications and SCTP_RCVINFO control
+// messages are turned on to tell new associations and their data apart.
+func (ln *SCTPListener) enableAutoPeelOff() error {
+	p := &autoPeelOff{sem: make(chan struct{}, 1)}
+	err := ln.fd.control(func(fd int) (err error) {
+		if p.assocChange, err = SCTPGetEvent(fd, SCTP_FUTURE_ASSOC, SCTP_ASSOC_CHANGE); err != nil {
+			return err
+		}
+		if p.rcvInfo, err = SCTPGetRecvRcvInfo(fd); err != nil {
+			return err
+		}
+		if err = SCTPSetEvent(fd, SCTP_FUTURE_ASSOC, SCTP_ASSOC_CHANGE, true); err != nil {
+			return err
+		}
+		return SCTPSetRecvRcvInfo(fd, true)
+	})
+	if err != nil {
+		return err
+	}
+	ln.peelOff = p
+	return nil
+}
+
+// acceptPeelOff waits for an association to come up and peels it off. The
+// messages on the listening socket are only peeked at until the
+// association they belong to is known: the kernel moves the messages
+// queued for an association to its peeled-off socket, so data that arrived
+// before the peel-off is read from the returned SCTPConn. Notifications
+// are consumed, and passed on if notifications are enabled.
+func (ln *SCTPListener) acceptPeelOff(ctx context.Context) (*SCTPConn, error) {
+	p := ln.peelOff
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+	defer func() { <-p.sem }()
 
-	if err := ln.Listen(); err != nil {
-		return nil, err
+	for {
+		var (
+			flags int
+			info  *RecvMsgInfo
+		)
+		// a notification left by an accept given up while passing it
+		// on goes first, so that an SCTP_COMM_UP is not lost
+		if p.notif == nil {
+			err := ln.fd.read(ctx, func(s int) (err error) {
+				flags, info, err = sctpPeekMsg(s)
+				return
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			flags = MSG_NOTIFICATION
+		}
+
+		var id AssocID
+		data := flags&MSG_NOTIFICATION == 0
+		if data {
+			// data of an association set up before Accept was
+			// called, or whose SCTP_COMM_UP went missing
+			switch {
+			case info.RcvInfo != nil:
+				id = info.RcvInfo.AssocID
+			case info.SndRcvInfo != nil:
+				id = info.SndRcvInfo.AssocID
+			default:
+				return nil, fmt.Errorf("no association ID on message")
+			}
+		} else {
+			var err error
+			notif := p.notif
+			if notif == nil {
+				if notif, err = ln.readNotification(ctx); err != nil {
+					return nil, err
+				}
+				if notif == nil {
+					continue
+				}
+				p.notif = notif
+				err = ln.deliverNotification(ctx, notif)
+			} else {
+				// queued by the notifier already, which keeps
+				// what it could not send
+				err = ln.deliverNotification(ctx, nil)
+			}
+			if err != nil {
+				return nil, err
+			}
+			p.notif = nil
+			ac := notif.GetAssociationChange()
+			if ac == nil || ac.State != SCTP_COMM_UP {
+				continue
+			}
+			id = ac.AssocID
+		}
+
+		c, err := ln.peelOffAssoc(id)
+		if err == syscall.EINVAL {
+			// the association is gone already
+			if data {
+				if err := ln.discardMsg(ctx); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		return c, err
 	}
-	return ln, nil
 }
 
-// AcceptSCTP waits for and returns the next SCTP connection to the listener.
-func (ln *SCTPListener) AcceptSCTP() (*SCTPConn, error) {
-	if ln.socketMode == OneToMany {
-		return nil, fmt.Errorf("Calling Accept on OneToMany socket is invalid")
+// readNotification consumes the notification at the head of the socket.
+// It returns nil if the notification cannot be parsed, which is counted as
+// dropped.
+func (ln *SCTPListener) readNotification(ctx context.Context) (*Notification, error) {
+	b := make([]byte, 512)
+	var msg []byte
+	for {
+		var n, flags int
+		err := ln.fd.read(ctx, func(s int) (err error) {
+			n, _, flags, err = SCTPRecvMsg(s, b)
+			return
+		})
+		if err != nil {
+			return nil, err
+		}
+		msg = append(msg, b[:n]...)
+		if flags&MSG_EOR != 0 {
+			break
+		}
 	}
 
-	fd, err := SCTPAccept(ln.FD())
+	ntf := ln.fd.notifications()
+	notif, err := SCTPParseNotification(msg)
 	if err != nil {
-		return nil, err
+		if ntf != nil {
+			atomic.AddUint64(&ntf.dropped, 1)
+		}
+		return nil, nil
+	}
+	if ntf != nil {
+		ntf.track(notif)
 	}
-	blocking, err := ln.GetNonblocking()
+	return notif, nil
+}
+
+// deliverNotification passes notif on if notifications are enabled,
+// behind those not sent yet. A nil notif only sends those.
+func (ln *SCTPListener) deliverNotification(ctx context.Context, notif *Notification) error {
+	ntf := ln.fd.notifications()
+	if ntf == nil {
+		return nil
+	}
+	return ntf.deliver(ctx, ln.fd.rdeadline.deadline(), notif)
+}
+
+// discardMsg consumes the message at the head of the socket.
+func (ln *SCTPListener) discardMsg(ctx context.Context) error {
+	b := make([]byte, 4096)
+	for {
+		var flags int
+		err := ln.fd.read(ctx, func(s int) (err error) {
+			_, _, flags, err = SCTPRecvMsg(s, b)
+			return
+		})
+		if err != nil || flags&MSG_EOR != 0 {
+			return err
+		}
+	}
+}
+
+// peelOffAssoc peels the association off and puts back on its socket the
+// settings changed by enableAutoPeelOff.
+func (ln *SCTPListener) peelOffAssoc(id AssocID) (*SCTPConn, error) {
+	p := ln.peelOff
+	var fd int
+	err := ln.fd.control(func(s int) (err error) {
+		fd, err = SCTPPeelOff(s, id)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
-	conn := &SCTPConn{fd: int32(fd)}
-
-	err = conn.SetNonblocking(blocking)
+	if !p.assocChange {
+		err = SCTPSetEvent(fd, 0, SCTP_ASSOC_CHANGE, false)
+	}
+	if err == nil && !p.rcvInfo {
+		err = SCTPSetRecvRcvInfo(fd, false)
+	}
 	if err != nil {
+		syscall.Close(fd)
 		return nil, err
 	}
+	return newSCTPConn(fd, ln.fd.isNonblocking())
+}
 
-	return conn, nil
-
+// Addr returns the listener's network address.
+func (ln *SCTPListener) Addr() net.Addr {
+	return ln.LocalAddr()
 }
 
 // Accept waits for and returns the next connection connection to the listener.
@@ -62,18 +290,110 @@ func (ln *SCTPListener) Accept() (net.Conn, error) {
 	return ln.AcceptSCTP()
 }
 
+// SetDeadline sets the read and write deadlines of the listener. A
+// listener run by an AssociationManager takes no read deadline, which
+// would end the manager's reads.
+func (ln *SCTPListener) SetDeadline(t time.Time) error {
+	if atomic.LoadInt32(&ln.managed) == 1 {
+		return fmt.Errorf("Invalid state: SetDeadline on managed listener not allowed")
+	}
+	return ln.SCTPConn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the listener, which must not
+// be run by an AssociationManager.
+func (ln *SCTPListener) SetReadDeadline(t time.Time) error {
+	if atomic.LoadInt32(&ln.managed) == 1 {
+		return fmt.Errorf("Invalid state: SetReadDeadline on managed listener not allowed")
+	}
+	return ln.SCTPConn.SetReadDeadline(t)
+}
+
 func (ln *SCTPListener) SCTPRead(b []byte) (int, *OOBMessage, int, error) {
+	return ln.ReadMsgContext(context.Background(), b)
+}
+
+func (ln *SCTPListener) ReadMsgContext(ctx context.Context, b []byte) (int, *OOBMessage, int, error) {
 	if ln.socketMode == OneToOne {
 		return -1, nil, -1, fmt.Errorf("Invalid state: SCTPRead on OneToOne socket not allowed")
 	}
 
-	return ln.SCTPConn.SCTPRead(b)
+	return ln.SCTPConn.ReadMsgContext(ctx, b)
 }
 
 func (ln *SCTPListener) SCTPWrite(b []byte, info *SndRcvInfo) (int, error) {
+	return ln.WriteMsgContext(context.Background(), b, info)
+}
+
+func (ln *SCTPListener) WriteMsgContext(ctx context.Context, b []byte, info *SndRcvInfo) (int, error) {
 	if ln.socketMode == OneToOne {
 		return -1, fmt.Errorf("Invalid state: SCTPWrite on OneToOne socket not allowed")
 	}
 
-	return ln.SCTPConn.SCTPWrite(b, info)
+	return ln.SCTPConn.WriteMsgContext(ctx, b, info)
+}
+
+func (ln *SCTPListener) SendMsg(b []byte, info *SndInfo) (int, error) {
+	if ln.socketMode == OneToOne {
+		return -1, fmt.Errorf("Invalid state: SendMsg on OneToOne socket not allowed")
+	}
+
+	return ln.SCTPConn.SendMsg(b, info)
+}
+
+func (ln *SCTPListener) SendMsgPR(b []byte, info *SndInfo, pr *PRInfo) (int, error) {
+	if ln.socketMode == OneToOne {
+		return -1, fmt.Errorf("Invalid state: SendMsgPR on OneToOne socket not allowed")
+	}
+
+	return ln.SCTPConn.SendMsgPR(b, info, pr)
+}
+
+func (ln *SCTPListener) RecvMsg(b []byte) (int, *RecvMsgInfo, int, error) {
+	if ln.socketMode == OneToOne {
+		return -1, nil, -1, fmt.Errorf("Invalid state: RecvMsg on OneToOne socket not allowed")
+	}
+
+	return ln.SCTPConn.RecvMsg(b)
+}
+
+func (ln *SCTPListener) ReadMsgSCTP(b []byte) (int, *RecvMsgInfo, int, *SCTPAddr, error) {
+	if ln.socketMode == OneToOne {
+		return -1, nil, -1, nil, fmt.Errorf("Invalid state: ReadMsgSCTP on OneToOne socket not allowed")
+	}
+
+	return ln.SCTPConn.ReadMsgSCTP(b)
+}
+
+func (ln *SCTPListener) WriteMsgSCTP(b []byte, info *SndInfo, raddr *SCTPAddr) (int, error) {
+	if ln.socketMode == OneToOne {
+		return -1, fmt.Errorf("Invalid state: WriteMsgSCTP on OneToOne socket not allowed")
+	}
+
+	return ln.SCTPConn.WriteMsgSCTP(b, info, raddr)
+}
+
+// ReadFrom reads the next message, or the next part of a message larger
+// than b, and returns the peer address it came from, making a OneToMany
+// listener a net.PacketConn. Notifications are skipped.
+func (ln *SCTPListener) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		n, _, flags, from, err := ln.ReadMsgSCTP(b)
+		if err != nil {
+			return 0, nil, err
+		}
+		if flags&MSG_NOTIFICATION == 0 {
+			return n, from, nil
+		}
+	}
+}
+
+// WriteTo sends b as one message on stream 0 to addr, which must be an
+// *SCTPAddr. An association to addr is set up if there is none yet.
+func (ln *SCTPListener) WriteTo(b []byte, addr net.Addr) (int, error) {
+	raddr, ok := addr.(*SCTPAddr)
+	if !ok {
+		return 0, &net.AddrError{Err: "non-SCTP address", Addr: fmt.Sprint(addr)}
+	}
+	return ln.WriteMsgSCTP(b, nil, raddr)
 }
@@ -43,6 +43,41 @@ const (
 	SCTP_DELAYED_ACK  = SCTP_DELAYED_ACK_TIME
 	SCTP_DELAYED_SACK = SCTP_DELAYED_ACK_TIME
 
+	SCTP_FRAGMENT_INTERLEAVE    = 18
+	SCTP_PARTIAL_DELIVERY_POINT = 19
+	SCTP_GET_ASSOC_NUMBER       = 28
+	SCTP_GET_ASSOC_ID_LIST      = 29
+	SCTP_MAX_BURST              = 20
+	SCTP_AUTH_CHUNK             = 21
+	SCTP_HMAC_IDENT             = 22
+	SCTP_AUTH_KEY               = 23
+	SCTP_AUTH_ACTIVE_KEY        = 24
+	SCTP_AUTH_DELETE_KEY        = 25
+	SCTP_PEER_AUTH_CHUNKS       = 26
+	SCTP_LOCAL_AUTH_CHUNKS      = 27
+	SCTP_AUTO_ASCONF            = 30
+	SCTP_PEER_ADDR_THLDS        = 31
+	SCTP_RECVRCVINFO            = 32
+	SCTP_RECVNXTINFO            = 33
+	SCTP_AUTH_DEACTIVATE_KEY    = 35
+	SCTP_GET_ASSOC_STATS        = 112
+	SCTP_PR_SUPPORTED           = 113
+	SCTP_DEFAULT_PRINFO         = 114
+	SCTP_PR_ASSOC_STATUS        = 115
+	SCTP_PR_STREAM_STATUS       = 116
+	SCTP_RECONFIG_SUPPORTED     = 117
+	SCTP_ENABLE_STREAM_RESET    = 118
+	SCTP_RESET_STREAMS          = 119
+	SCTP_RESET_ASSOC            = 120
+	SCTP_ADD_STREAMS            = 121
+	SCTP_STREAM_SCHEDULER       = 123
+	SCTP_STREAM_SCHEDULER_VALUE = 124
+	SCTP_INTERLEAVING_SUPPORTED = 125
+	SCTP_EVENT                  = 127
+	SCTP_ASCONF_SUPPORTED       = 128
+	SCTP_AUTH_SUPPORTED         = 129
+	SCTP_ECN_SUPPORTED          = 130
+
 	SCTP_SOCKOPT_BINDX_ADD = 100
 	SCTP_SOCKOPT_BINDX_REM = 101
 	SCTP_SOCKOPT_PEELOFF   = 102
@@ -63,8 +98,28 @@ const (
 	SCTP_EVENT_ADAPTATION_LAYER
 	SCTP_EVENT_AUTHENTICATION
 	SCTP_EVENT_SENDER_DRY
+	SCTP_EVENT_STREAM_RESET
+	SCTP_EVENT_ASSOC_RESET
+	SCTP_EVENT_STREAM_CHANGE
+	SCTP_EVENT_SEND_FAILURE_EVENT
+
+	// SCTP_EVENT_LEGACY covers the events that fit in the SCTP_EVENTS
+	// struct understood by every kernel.
+	SCTP_EVENT_LEGACY = SCTP_EVENT_DATA_IO | SCTP_EVENT_ASSOCIATION | SCTP_EVENT_ADDRESS | SCTP_EVENT_SEND_FAILURE | SCTP_EVENT_PEER_ERROR | SCTP_EVENT_SHUTDOWN | SCTP_EVENT_PARTIAL_DELIVERY | SCTP_EVENT_ADAPTATION_LAYER | SCTP_EVENT_AUTHENTICATION | SCTP_EVENT_SENDER_DRY
+
+	SCTP_EVENT_ALL = SCTP_EVENT_LEGACY | SCTP_EVENT_STREAM_RESET | SCTP_EVENT_ASSOC_RESET | SCTP_EVENT_STREAM_CHANGE | SCTP_EVENT_SEND_FAILURE_EVENT
+)
+
+// AssocID identifies an association of a socket. On a OneToOne socket the
+// association can also be referred to as 0.
+type AssocID int32
 
-	SCTP_EVENT_ALL = SCTP_EVENT_DATA_IO | SCTP_EVENT_ASSOCIATION | SCTP_EVENT_ADDRESS | SCTP_EVENT_SEND_FAILURE | SCTP_EVENT_PEER_ERROR | SCTP_EVENT_SHUTDOWN | SCTP_EVENT_PARTIAL_DELIVERY | SCTP_EVENT_ADAPTATION_LAYER | SCTP_EVENT_AUTHENTICATION | SCTP_EVENT_SENDER_DRY
+// Special association IDs accepted by options that can be scoped to an
+// association on OneToMany sockets.
+const (
+	SCTP_FUTURE_ASSOC = AssocID(iota)
+	SCTP_CURRENT_ASSOC
+	SCTP_ALL_ASSOC
 )
 
 type SCTPNotificationType uint16
@@ -80,8 +135,47 @@ const (
 	SCTP_ADAPTATION_INDICATION
 	SCTP_AUTHENTICATION_INDICATION
 	SCTP_SENDER_DRY_EVENT
+	SCTP_STREAM_RESET_EVENT
+	SCTP_ASSOC_RESET_EVENT
+	SCTP_STREAM_CHANGE_EVENT
+	SCTP_SEND_FAILED_EVENT
 )
 
+// eventFlag returns the SCTP_EVENT_* flag subscribing to notifications of
+// type n, or 0 if there is none.
+func (n SCTPNotificationType) eventFlag() int {
+	switch n {
+	case SCTP_ASSOC_CHANGE:
+		return SCTP_EVENT_ASSOCIATION
+	case SCTP_PEER_ADDR_CHANGE:
+		return SCTP_EVENT_ADDRESS
+	case SCTP_SEND_FAILED:
+		return SCTP_EVENT_SEND_FAILURE
+	case SCTP_REMOTE_ERROR:
+		return SCTP_EVENT_PEER_ERROR
+	case SCTP_SHUTDOWN_EVENT:
+		return SCTP_EVENT_SHUTDOWN
+	case SCTP_PARTIAL_DELIVERY_EVENT:
+		return SCTP_EVENT_PARTIAL_DELIVERY
+	case SCTP_ADAPTATION_INDICATION:
+		return SCTP_EVENT_ADAPTATION_LAYER
+	case SCTP_AUTHENTICATION_INDICATION:
+		return SCTP_EVENT_AUTHENTICATION
+	case SCTP_SENDER_DRY_EVENT:
+		return SCTP_EVENT_SENDER_DRY
+	case SCTP_STREAM_RESET_EVENT:
+		return SCTP_EVENT_STREAM_RESET
+	case SCTP_ASSOC_RESET_EVENT:
+		return SCTP_EVENT_ASSOC_RESET
+	case SCTP_STREAM_CHANGE_EVENT:
+		return SCTP_EVENT_STREAM_CHANGE
+	case SCTP_SEND_FAILED_EVENT:
+		return SCTP_EVENT_SEND_FAILURE_EVENT
+	default:
+		return 0
+	}
+}
+
 func (n SCTPNotificationType) String() string {
 	switch n {
 	case SCTP_ASSOC_CHANGE:
@@ -102,8 +196,16 @@ func (n SCTPNotificationType) String() string {
 		return "SCTP_AUTHENTICATION_INDICATION"
 	case SCTP_SENDER_DRY_EVENT:
 		return "SCTP_SENDER_DRY_EVENT"
+	case SCTP_STREAM_RESET_EVENT:
+		return "SCTP_STREAM_RESET_EVENT"
+	case SCTP_ASSOC_RESET_EVENT:
+		return "SCTP_ASSOC_RESET_EVENT"
+	case SCTP_STREAM_CHANGE_EVENT:
+		return "SCTP_STREAM_CHANGE_EVENT"
+	case SCTP_SEND_FAILED_EVENT:
+		return "SCTP_SEND_FAILED_EVENT"
 	default:
-		panic(fmt.Sprintf("Unknown notification type: %d", n))
+		return fmt.Sprintf("SCTPNotificationType(%d)", uint16(n))
 	}
 }
 
@@ -117,6 +219,7 @@ const (
 	SCTP_CMSG_SNDINFO
 	SCTP_CMSG_RCVINFO
 	SCTP_CMSG_NXTINFO
+	SCTP_CMSG_PRINFO
 )
 
 const (
@@ -124,13 +227,116 @@ const (
 	SCTP_ADDR_OVER
 	SCTP_ABORT
 	SCTP_SACK_IMMEDIATELY
-	SCTP_EOF
+
+	// SCTP_EOF does not follow the bits above: Linux reuses MSG_FIN
+	SCTP_EOF = syscall.MSG_FIN
 )
 
 const (
 	SCTP_MAX_STREAM = 0xffff
 )
 
+// Requests accepted by SCTP_ENABLE_STREAM_RESET.
+const (
+	SCTP_ENABLE_RESET_STREAM_REQ = 0x01
+	SCTP_ENABLE_RESET_ASSOC_REQ  = 0x02
+	SCTP_ENABLE_CHANGE_ASSOC_REQ = 0x04
+	SCTP_ENABLE_STRRESET_MASK    = 0x07
+)
+
+// Directions of SCTP_RESET_STREAMS.
+const (
+	SCTP_STREAM_RESET_INCOMING = 0x01
+	SCTP_STREAM_RESET_OUTGOING = 0x02
+)
+
+// Flags of the stream reconfiguration notifications.
+const (
+	SCTP_STREAM_RESET_INCOMING_SSN = 0x0001
+	SCTP_STREAM_RESET_OUTGOING_SSN = 0x0002
+	SCTP_STREAM_RESET_DENIED       = 0x0004
+	SCTP_STREAM_RESET_FAILED       = 0x0008
+
+	SCTP_ASSOC_RESET_DENIED = 0x0004
+	SCTP_ASSOC_RESET_FAILED = 0x0008
+
+	SCTP_STREAM_CHANGE_DENIED = 0x0004
+	SCTP_STREAM_CHANGE_FAILED = 0x0008
+)
+
+// HMAC identifiers accepted by SCTP_HMAC_IDENT.
+const (
+	SCTP_AUTH_HMAC_ID_SHA1   = 1
+	SCTP_AUTH_HMAC_ID_SHA256 = 3
+)
+
+// Chunk types commonly passed to SCTP_AUTH_CHUNK.
+const (
+	SCTP_CID_DATA       = 0x00
+	SCTP_CID_ASCONF_ACK = 0x80
+	SCTP_CID_ASCONF     = 0xc1
+)
+
+// Indications of the SCTP_AUTHENTICATION_INDICATION notification.
+const (
+	SCTP_AUTH_NEW_KEY = iota
+	SCTP_AUTH_FREE_KEY
+	SCTP_AUTH_NO_AUTH
+)
+
+// SCTPStreamScheduler selects how outgoing streams share the association
+// (RFC 8260).
+type SCTPStreamScheduler uint32
+
+const (
+	// SCTP_SS_FCFS sends messages in the order they were queued,
+	// whatever their stream.
+	SCTP_SS_FCFS = SCTPStreamScheduler(iota)
+	// SCTP_SS_PRIO serves streams by priority, lower values first, and
+	// streams of equal priority round-robin.
+	SCTP_SS_PRIO
+	// SCTP_SS_RR serves streams round-robin, one message at a time.
+	SCTP_SS_RR
+	// SCTP_SS_FC serves the stream that has sent the fewest bytes.
+	SCTP_SS_FC
+	// SCTP_SS_WFQ is like SCTP_SS_FC with the bytes of each stream
+	// weighted by its value.
+	SCTP_SS_WFQ
+
+	SCTP_SS_DEFAULT = SCTP_SS_FCFS
+)
+
+// PRPolicy is a PR-SCTP policy (RFC 7496). On the legacy SndRcvInfo path,
+// the policy goes in Flags and its value in TTL.
+type PRPolicy uint16
+
+const (
+	SCTP_PR_SCTP_NONE = PRPolicy(0x0000)
+	SCTP_PR_SCTP_TTL  = PRPolicy(0x0010)
+	SCTP_PR_SCTP_RTX  = PRPolicy(0x0020)
+	SCTP_PR_SCTP_PRIO = PRPolicy(0x0030)
+
+	// SCTP_PR_SCTP_ALL sums up all policies in PR-SCTP status queries.
+	SCTP_PR_SCTP_ALL = PRPolicy(0x0080)
+)
+
+const (
+	SPP_HB_ENABLE = 1 << iota
+	SPP_HB_DISABLE
+	SPP_HB_DEMAND
+	SPP_PMTUD_ENABLE
+	SPP_PMTUD_DISABLE
+	SPP_SACKDELAY_ENABLE
+	SPP_SACKDELAY_DISABLE
+	SPP_HB_TIME_IS_ZERO
+	SPP_IPV6_FLOWLABEL
+	SPP_DSCP
+
+	SPP_HB        = SPP_HB_ENABLE | SPP_HB_DISABLE
+	SPP_PMTUD     = SPP_PMTUD_ENABLE | SPP_PMTUD_DISABLE
+	SPP_SACKDELAY = SPP_SACKDELAY_ENABLE | SPP_SACKDELAY_DISABLE
+)
+
 type SCTPState uint16
 
 const (
@@ -154,7 +360,7 @@ func (s SCTPState) String() string {
 	case SCTP_CANT_STR_ASSOC:
 		return "SCTP_CANT_STR_ASSOC"
 	default:
-		panic(fmt.Sprintf("Unknown SCTPState: %d", s))
+		return fmt.Sprintf("SCTPState(%d)", uint16(s))
 	}
 }
 
@@ -200,6 +406,22 @@ const (
 	OneToMany
 )
 
+// NotificationOverflow selects what happens to a notification received
+// while the channel returned by SCTPConn.Notifications is full.
+type NotificationOverflow int
+
+const (
+	// NotificationBlock holds up the read that received the notification
+	// until the consumer makes room. No notification is lost, but data is
+	// not read in the meantime, so a slow consumer eventually stalls the
+	// peer through SCTP flow control.
+	NotificationBlock = NotificationOverflow(iota)
+
+	// NotificationDrop discards the notification and counts it in
+	// SCTPConn.DroppedNotifications. Reads are never held up.
+	NotificationDrop
+)
+
 type PeerChangeState uint32
 
 const (
@@ -208,4 +430,75 @@ const (
 	SCTP_ADDR_REMOVED
 	SCTP_ADDR_ADDED
 	SCTP_ADDR_MADE_PRIM
+	SCTP_ADDR_CONFIRMED
+	SCTP_ADDR_POTENTIALLY_FAILED
+)
+
+// PeerAddrState is the state of a path as reported by
+// SCTP_GET_PEER_ADDR_INFO.
+type PeerAddrState int32
+
+const (
+	SCTP_INACTIVE = PeerAddrState(iota)
+	SCTP_PF
+	SCTP_ACTIVE
+	SCTP_UNCONFIRMED
+	SCTP_UNKNOWN = PeerAddrState(0xffff)
 )
+
+func (s PeerAddrState) String() string {
+	switch s {
+	case SCTP_INACTIVE:
+		return "SCTP_INACTIVE"
+	case SCTP_PF:
+		return "SCTP_PF"
+	case SCTP_ACTIVE:
+		return "SCTP_ACTIVE"
+	case SCTP_UNCONFIRMED:
+		return "SCTP_UNCONFIRMED"
+	case SCTP_UNKNOWN:
+		return "SCTP_UNKNOWN"
+	default:
+		return fmt.Sprintf("PeerAddrState(%d)", int32(s))
+	}
+}
+
+// AssocState is the state of an association as reported by SCTP_STATUS.
+type AssocState int32
+
+const (
+	SCTP_EMPTY = AssocState(iota)
+	SCTP_CLOSED
+	SCTP_COOKIE_WAIT
+	SCTP_COOKIE_ECHOED
+	SCTP_ESTABLISHED
+	SCTP_SHUTDOWN_PENDING
+	SCTP_SHUTDOWN_SENT
+	SCTP_SHUTDOWN_RECEIVED
+	SCTP_SHUTDOWN_ACK_SENT
+)
+
+func (s AssocState) String() string {
+	switch s {
+	case SCTP_EMPTY:
+		return "SCTP_EMPTY"
+	case SCTP_CLOSED:
+		return "SCTP_CLOSED"
+	case SCTP_COOKIE_WAIT:
+		return "SCTP_COOKIE_WAIT"
+	case SCTP_COOKIE_ECHOED:
+		return "SCTP_COOKIE_ECHOED"
+	case SCTP_ESTABLISHED:
+		return "SCTP_ESTABLISHED"
+	case SCTP_SHUTDOWN_PENDING:
+		return "SCTP_SHUTDOWN_PENDING"
+	case SCTP_SHUTDOWN_SENT:
+		return "SCTP_SHUTDOWN_SENT"
+	case SCTP_SHUTDOWN_RECEIVED:
+		return "SCTP_SHUTDOWN_RECEIVED"
+	case SCTP_SHUTDOWN_ACK_SENT:
+		return "SCTP_SHUTDOWN_ACK_SENT"
+	default:
+		return fmt.Sprintf("AssocState(%d)", int32(s))
+	}
+}
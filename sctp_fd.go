@@ -0,0 +1,364 @@
+package sctp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	stdsyscall "syscall"
+	"time"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// sctpFD is an SCTP socket registered with the runtime network poller.
+// The descriptor is always O_NONBLOCK at the OS level; blocking reads and
+// writes park the calling goroutine until the socket is ready or the
+// deadline expires. When the user asks for a non-blocking socket, EAGAIN is
+// handed back to the caller instead of waiting.
+//
+// Every use of the descriptor goes through rc, whose poll.FD counts the
+// references held by in-flight calls: closing the file unblocks waiting
+// calls and releases the descriptor only once the last of them returns, so
+// a descriptor number can never be reused under a running call.
+type sctpFD struct {
+	sysfd       int
+	nonblocking int32
+	closing     int32
+	file        *os.File
+	rc          stdsyscall.RawConn
+
+	rdeadline pollDeadline
+	wdeadline pollDeadline
+
+	nmu      sync.Mutex
+	notifier *notifier
+}
+
+// newSCTPFD takes ownership of sysfd. The descriptor is closed if an error
+// is returned.
+func newSCTPFD(sysfd int, nonblocking bool) (*sctpFD, error) {
+	if err := syscall.SetNonblock(sysfd, true); err != nil {
+		syscall.Close(sysfd)
+		return nil, err
+	}
+	f := os.NewFile(uintptr(sysfd), "sctp")
+	rc, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	fd := &sctpFD{
+		sysfd: sysfd,
+		file:  f,
+		rc:    rc,
+	}
+	fd.rdeadline.set = f.SetReadDeadline
+	fd.wdeadline.set = f.SetWriteDeadline
+	fd.setNonblocking(nonblocking)
+	return fd, nil
+}
+
+func (fd *sctpFD) isNonblocking() bool {
+	return atomic.LoadInt32(&fd.nonblocking) == 1
+}
+
+func (fd *sctpFD) setNonblocking(nonblocking bool) {
+	atomic.StoreInt32(&fd.nonblocking, int32(boolint(nonblocking)))
+}
+
+func (fd *sctpFD) isClosing() bool {
+	return atomic.LoadInt32(&fd.closing) == 1
+}
+
+// closeErr reports failures of calls that raced with close as
+// net.ErrClosed.
+func (fd *sctpFD) closeErr(err error) error {
+	if err != nil && fd.isClosing() {
+		return net.ErrClosed
+	}
+	return err
+}
+
+// enableNotifications diverts notifications read from fd to a channel of
+// the given size.
+func (fd *sctpFD) enableNotifications(size int, overflow NotificationOverflow) error {
+	fd.nmu.Lock()
+	defer fd.nmu.Unlock()
+	if fd.isClosing() {
+		return net.ErrClosed
+	}
+	if fd.notifier != nil {
+		return errors.New("notifications already enabled")
+	}
+	fd.notifier = newNotifier(size, overflow)
+	return nil
+}
+
+// notifications returns the notifier set up by enableNotifications, if
+// any.
+func (fd *sctpFD) notifications() *notifier {
+	fd.nmu.Lock()
+	defer fd.nmu.Unlock()
+	return fd.notifier
+}
+
+// control runs f with the descriptor, which stays valid until f returns.
+func (fd *sctpFD) control(f func(s int) error) error {
+	var operr error
+	err := fd.rc.Control(func(s uintptr) {
+		operr = f(int(s))
+	})
+	if err != nil {
+		return fd.closeErr(err)
+	}
+	return fd.closeErr(operr)
+}
+
+// read calls f until it stops returning EAGAIN, waiting for the socket to
+// become readable in between. The wait is abandoned with ctx.Err() once
+// ctx is done.
+func (fd *sctpFD) read(ctx context.Context, f func(s int) error) error {
+	nonblocking := fd.isNonblocking()
+	var operr error
+	err := fd.rdeadline.wait(ctx, func() error {
+		return fd.rc.Read(func(s uintptr) bool {
+			operr = f(int(s))
+			return nonblocking || operr != syscall.EAGAIN
+		})
+	})
+	if err != nil {
+		return fd.closeErr(err)
+	}
+	return fd.closeErr(operr)
+}
+
+// write calls f until it stops returning EAGAIN, waiting for the socket to
+// become writable in between. The wait is abandoned with ctx.Err() once
+// ctx is done.
+func (fd *sctpFD) write(ctx context.Context, f func(s int) error) error {
+	nonblocking := fd.isNonblocking()
+	var operr error
+	err := fd.wdeadline.wait(ctx, func() error {
+		return fd.rc.Write(func(s uintptr) bool {
+			operr = f(int(s))
+			return nonblocking || operr != syscall.EAGAIN
+		})
+	})
+	if err != nil {
+		return fd.closeErr(err)
+	}
+	return fd.closeErr(operr)
+}
+
+func (fd *sctpFD) connect(ctx context.Context, raddr *SCTPAddr) (AssocID, error) {
+	var (
+		id   AssocID
+		mode SCTPSocketMode
+	)
+	err := fd.control(func(s int) (err error) {
+		if mode, err = SCTPGetSocketMode(s); err != nil {
+			return err
+		}
+		id, err = SCTPConnect(s, raddr)
+		return err
+	})
+	switch err {
+	case syscall.EINPROGRESS, syscall.EALREADY, syscall.EINTR:
+	default:
+		return id, err
+	}
+	if fd.isNonblocking() {
+		return id, err
+	}
+	if mode == OneToMany {
+		// the association is brought up in the background and
+		// announced with SCTP_COMM_UP
+		return id, nil
+	}
+
+	var operr error
+	err = fd.wdeadline.wait(ctx, func() error {
+		return fd.rc.Write(func(s uintptr) bool {
+			var nerr int
+			nerr, operr = syscall.GetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_ERROR)
+			if operr != nil {
+				return true
+			}
+			switch e := syscall.Errno(nerr); e {
+			case syscall.EINPROGRESS, syscall.EALREADY, syscall.EINTR:
+				return false
+			case 0:
+				if _, operr = syscall.Getpeername(int(s)); operr == syscall.ENOTCONN {
+					operr = nil
+					return false
+				}
+				return true
+			default:
+				operr = e
+				return true
+			}
+		})
+	})
+	if err != nil {
+		return id, fd.closeErr(err)
+	}
+	return id, fd.closeErr(operr)
+}
+
+func (fd *sctpFD) setDeadline(t time.Time) error {
+	if err := fd.rdeadline.setDeadline(t); err != nil {
+		return err
+	}
+	return fd.wdeadline.setDeadline(t)
+}
+
+func (fd *sctpFD) setReadDeadline(t time.Time) error {
+	return fd.rdeadline.setDeadline(t)
+}
+
+func (fd *sctpFD) setWriteDeadline(t time.Time) error {
+	return fd.wdeadline.setDeadline(t)
+}
+
+// close marks fd as closing, shuts the association down and closes the
+// file and the notification channel. Only the first call has any effect;
+// later ones fail with net.ErrClosed.
+func (fd *sctpFD) close() error {
+	if !atomic.CompareAndSwapInt32(&fd.closing, 0, 1) {
+		return net.ErrClosed
+	}
+	fd.rc.Control(func(s uintptr) {
+		sctpShutdown(int(s))
+	})
+	err := fd.file.Close()
+	if n := fd.notifications(); n != nil {
+		n.close()
+	}
+	return err
+}
+
+// aLongTimeAgo is a non-zero time, far in the past, used to interrupt
+// operations waiting in the poller.
+var aLongTimeAgo = time.Unix(1, 0)
+
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+}
+
+// pollDeadline keeps the deadline set by the user for one direction of an
+// sctpFD. A cancelled context interrupts the poller by moving the deadline
+// into the past. Since this wakes up whichever operation is waiting at the
+// time, operations that did not ask to be cancelled are retried once the
+// user deadline is back in place.
+type pollDeadline struct {
+	set func(time.Time) error
+
+	mu      sync.Mutex
+	t       time.Time
+	cancels int
+	cleared chan struct{}
+}
+
+func (d *pollDeadline) setDeadline(t time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.t = t
+	if d.cancels > 0 {
+		return nil
+	}
+	return d.set(t)
+}
+
+// deadline returns the deadline set by the user.
+func (d *pollDeadline) deadline() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.t
+}
+
+func (d *pollDeadline) interrupt() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancels == 0 {
+		d.cleared = make(chan struct{})
+	}
+	d.cancels++
+	d.set(aLongTimeAgo)
+}
+
+func (d *pollDeadline) release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cancels--
+	if d.cancels == 0 {
+		d.set(d.t)
+		close(d.cleared)
+	}
+}
+
+// spurious reports whether a timeout was caused by an interruption rather
+// than by the user deadline. The returned channel is closed once the user
+// deadline has been restored.
+func (d *pollDeadline) spurious() (<-chan struct{}, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.t.IsZero() && !time.Now().Before(d.t) {
+		return nil, false
+	}
+	if d.cancels == 0 {
+		return closedChan, true
+	}
+	return d.cleared, true
+}
+
+// wait runs op, interrupting it when ctx is done.
+func (d *pollDeadline) wait(ctx context.Context, op func() error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var err error
+		if done := ctx.Done(); done == nil {
+			err = op()
+		} else {
+			stop := make(chan struct{})
+			interrupted := make(chan bool)
+			go func() {
+				select {
+				case <-done:
+					d.interrupt()
+					interrupted <- true
+				case <-stop:
+					interrupted <- false
+				}
+			}()
+			err = op()
+			close(stop)
+			if <-interrupted {
+				d.release()
+			}
+		}
+
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		restored, ok := d.spurious()
+		if !ok {
+			return err
+		}
+		select {
+		case <-restored:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,652 @@
+package sctp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTruncatedNotification is returned, wrapped, by SCTPParseNotification
+// when a notification is shorter than its header or type requires. This
+// happens when the buffer used to read it was too small.
+var ErrTruncatedNotification = errors.New("truncated SCTP notification")
+
+const notificationHeaderSize = 8
+
+type NotificationHeader struct {
+	Type   SCTPNotificationType
+	Flags  uint16
+	Length uint32
+}
+
+// Notification is a notification read from a socket with MSG_NOTIFICATION
+// set. Data holds it as received; the Get methods return the decoded event,
+// or nil if the notification is of another type.
+type Notification struct {
+	Data []byte
+
+	header NotificationHeader
+	event  interface{}
+}
+
+func (n *Notification) Header() *NotificationHeader {
+	h := n.header
+	return &h
+}
+
+func (n *Notification) Type() SCTPNotificationType {
+	return n.header.Type
+}
+
+// AssocID returns the association the notification refers to, or 0 if the
+// notification type is unknown.
+func (n *Notification) AssocID() AssocID {
+	switch e := n.event.(type) {
+	case *AssociationChange:
+		return e.AssocID
+	case *PeerAddrChange:
+		return e.AssocID
+	case *RemoteError:
+		return e.AssocID
+	case *SendFailed:
+		return e.AssocID
+	case *SendFailedEvent:
+		return e.AssocID
+	case *Shutdown:
+		return e.AssocID
+	case *AdaptationIndication:
+		return e.AssocID
+	case *PartialDelivery:
+		return e.AssocID
+	case *Authentication:
+		return e.AssocID
+	case *SenderDry:
+		return e.AssocID
+	case *StreamResetEvent:
+		return e.AssocID
+	case *AssocResetEvent:
+		return e.AssocID
+	case *StreamChangeEvent:
+		return e.AssocID
+	default:
+		return 0
+	}
+}
+
+func (n *Notification) GetAssociationChange() *AssociationChange {
+	e, _ := n.event.(*AssociationChange)
+	return e
+}
+
+func (n *Notification) GetPeerAddrChange() *PeerAddrChange {
+	e, _ := n.event.(*PeerAddrChange)
+	return e
+}
+
+func (n *Notification) GetRemoteError() *RemoteError {
+	e, _ := n.event.(*RemoteError)
+	return e
+}
+
+func (n *Notification) GetSendFailed() *SendFailed {
+	e, _ := n.event.(*SendFailed)
+	return e
+}
+
+func (n *Notification) GetSendFailedEvent() *SendFailedEvent {
+	e, _ := n.event.(*SendFailedEvent)
+	return e
+}
+
+func (n *Notification) GetShutdown() *Shutdown {
+	e, _ := n.event.(*Shutdown)
+	return e
+}
+
+func (n *Notification) GetAdaptationIndication() *AdaptationIndication {
+	e, _ := n.event.(*AdaptationIndication)
+	return e
+}
+
+func (n *Notification) GetPartialDelivery() *PartialDelivery {
+	e, _ := n.event.(*PartialDelivery)
+	return e
+}
+
+func (n *Notification) GetAuthentication() *Authentication {
+	e, _ := n.event.(*Authentication)
+	return e
+}
+
+func (n *Notification) GetSenderDry() *SenderDry {
+	e, _ := n.event.(*SenderDry)
+	return e
+}
+
+func (n *Notification) GetStreamReset() *StreamResetEvent {
+	e, _ := n.event.(*StreamResetEvent)
+	return e
+}
+
+func (n *Notification) GetAssocReset() *AssocResetEvent {
+	e, _ := n.event.(*AssocResetEvent)
+	return e
+}
+
+func (n *Notification) GetStreamChange() *StreamChangeEvent {
+	e, _ := n.event.(*StreamChangeEvent)
+	return e
+}
+
+// AssociationChange is struct sctp_assoc_change. Info holds the ABORT
+// chunk or the supported features reported with the change, if any.
+type AssociationChange struct {
+	Type            SCTPNotificationType
+	Flags           uint16
+	Length          uint32
+	State           SCTPState
+	Error           uint16
+	OutboundStreams uint16
+	InboundStreams  uint16
+	AssocID         AssocID
+	Info            []byte
+}
+
+// PeerAddrChange is struct sctp_paddr_change.
+type PeerAddrChange struct {
+	Type    SCTPNotificationType
+	Flags   uint16
+	Length  uint32
+	Addr    net.IPAddr
+	Port    int
+	State   PeerChangeState
+	Error   uint32
+	AssocID AssocID
+}
+
+// RemoteError is struct sctp_remote_error. Info holds the ERROR chunk
+// received from the peer.
+type RemoteError struct {
+	Type    SCTPNotificationType
+	Flags   uint16
+	Length  uint32
+	Error   uint16
+	AssocID AssocID
+	Info    []byte
+}
+
+// SendFailed is struct sctp_send_failed. Data holds the message that
+// could not be sent.
+type SendFailed struct {
+	Type       SCTPNotificationType
+	Flags      uint16
+	Length     uint32
+	Error      uint32
+	SndRcvInfo SndRcvInfo
+	AssocID    AssocID
+	Data       []byte
+}
+
+// SendFailedEvent is struct sctp_send_failed_event, the replacement of
+// SendFailed delivered when subscribed through SCTP_EVENT_SEND_FAILURE_EVENT.
+type SendFailedEvent struct {
+	Type    SCTPNotificationType
+	Flags   uint16
+	Length  uint32
+	Error   uint32
+	SndInfo SndInfo
+	AssocID AssocID
+	Data    []byte
+}
+
+// Shutdown is struct sctp_shutdown_event.
+type Shutdown struct {
+	Type    SCTPNotificationType
+	Flags   uint16
+	Length  uint32
+	AssocID AssocID
+}
+
+// AdaptationIndication is struct sctp_adaptation_event. Indication is the
+// adaptation layer indication sent by the peer in its INIT or INIT-ACK.
+type AdaptationIndication struct {
+	Type       SCTPNotificationType
+	Flags      uint16
+	Length     uint32
+	Indication uint32
+	AssocID    AssocID
+}
+
+// PartialDelivery is struct sctp_pdapi_event. StreamID and SequenceNumber
+// are only reported by kernels 4.18 and later.
+type PartialDelivery struct {
+	Type           SCTPNotificationType
+	Flags          uint16
+	Length         uint32
+	Indication     uint32
+	AssocID        AssocID
+	StreamID       uint32
+	SequenceNumber uint32
+}
+
+// Authentication is struct sctp_authkey_event.
+type Authentication struct {
+	Type         SCTPNotificationType
+	Flags        uint16
+	Length       uint32
+	KeyNumber    uint16
+	AltKeyNumber uint16
+	Indication   uint32
+	AssocID      AssocID
+}
+
+type SenderDry struct {
+	Type    SCTPNotificationType
+	Flags   uint16
+	Length  uint32
+	AssocID AssocID
+}
+
+// StreamResetEvent is struct sctp_stream_reset_event. Flags tells the
+// direction of the reset with SCTP_STREAM_RESET_INCOMING_SSN and
+// SCTP_STREAM_RESET_OUTGOING_SSN, and whether it went through with
+// SCTP_STREAM_RESET_DENIED and SCTP_STREAM_RESET_FAILED. An empty Streams
+// means all streams.
+type StreamResetEvent struct {
+	Type    SCTPNotificationType
+	Flags   uint16
+	Length  uint32
+	AssocID AssocID
+	Streams []uint16
+}
+
+// AssocResetEvent is struct sctp_assoc_reset_event, carrying the TSNs the
+// association starts over from.
+type AssocResetEvent struct {
+	Type      SCTPNotificationType
+	Flags     uint16
+	Length    uint32
+	AssocID   AssocID
+	LocalTSN  uint32
+	RemoteTSN uint32
+}
+
+// StreamChangeEvent is struct sctp_stream_change_event, carrying the
+// number of streams after the change.
+type StreamChangeEvent struct {
+	Type            SCTPNotificationType
+	Flags           uint16
+	Length          uint32
+	AssocID         AssocID
+	InboundStreams  uint16
+	OutboundStreams uint16
+}
+
+// SCTPParseNotification decodes the notification in b. A notification
+// shorter than its type requires yields an error wrapping
+// ErrTruncatedNotification. Notifications of a type unknown to this package
+// are not an error; only their header is decoded.
+func SCTPParseNotification(b []byte) (*Notification, error) {
+	if len(b) < notificationHeaderSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTruncatedNotification, len(b))
+	}
+	h := NotificationHeader{
+		Type:   SCTPNotificationType(nativeEndian.Uint16(b[0:])),
+		Flags:  nativeEndian.Uint16(b[2:]),
+		Length: nativeEndian.Uint32(b[4:]),
+	}
+	if h.Length < notificationHeaderSize || uint64(h.Length) > uint64(len(b)) {
+		return nil, fmt.Errorf("%w: %v of %d bytes, have %d", ErrTruncatedNotification, h.Type, h.Length, len(b))
+	}
+	b = b[:h.Length]
+
+	need := func(size int) error {
+		if len(b) < size {
+			return fmt.Errorf("%w: %v of %d bytes, want at least %d", ErrTruncatedNotification, h.Type, len(b), size)
+		}
+		return nil
+	}
+
+	n := &Notification{Data: b, header: h}
+	switch h.Type {
+	case SCTP_ASSOC_CHANGE:
+		if err := need(20); err != nil {
+			return nil, err
+		}
+		n.event = &AssociationChange{
+			Type:            h.Type,
+			Flags:           h.Flags,
+			Length:          h.Length,
+			State:           SCTPState(nativeEndian.Uint16(b[8:])),
+			Error:           nativeEndian.Uint16(b[10:]),
+			OutboundStreams: nativeEndian.Uint16(b[12:]),
+			InboundStreams:  nativeEndian.Uint16(b[14:]),
+			AssocID:         AssocID(nativeEndian.Uint32(b[16:])),
+			Info:            tail(b, 20),
+		}
+	case SCTP_PEER_ADDR_CHANGE:
+		if err := need(148); err != nil {
+			return nil, err
+		}
+		addr, port, err := parseSockaddr(b[8:136])
+		if err != nil {
+			return nil, err
+		}
+		n.event = &PeerAddrChange{
+			Type:    h.Type,
+			Flags:   h.Flags,
+			Length:  h.Length,
+			Addr:    addr,
+			Port:    port,
+			State:   PeerChangeState(nativeEndian.Uint32(b[136:])),
+			Error:   nativeEndian.Uint32(b[140:]),
+			AssocID: AssocID(nativeEndian.Uint32(b[144:])),
+		}
+	case SCTP_REMOTE_ERROR:
+		if err := need(16); err != nil {
+			return nil, err
+		}
+		n.event = &RemoteError{
+			Type:    h.Type,
+			Flags:   h.Flags,
+			Length:  h.Length,
+			Error:   binary.BigEndian.Uint16(b[8:]),
+			AssocID: AssocID(nativeEndian.Uint32(b[12:])),
+			Info:    tail(b, 16),
+		}
+	case SCTP_SEND_FAILED:
+		if err := need(48); err != nil {
+			return nil, err
+		}
+		n.event = &SendFailed{
+			Type:       h.Type,
+			Flags:      h.Flags,
+			Length:     h.Length,
+			Error:      nativeEndian.Uint32(b[8:]),
+			SndRcvInfo: parseSndRcvInfo(b[12:44]),
+			AssocID:    AssocID(nativeEndian.Uint32(b[44:])),
+			Data:       tail(b, 48),
+		}
+	case SCTP_SEND_FAILED_EVENT:
+		if err := need(32); err != nil {
+			return nil, err
+		}
+		n.event = &SendFailedEvent{
+			Type:    h.Type,
+			Flags:   h.Flags,
+			Length:  h.Length,
+			Error:   nativeEndian.Uint32(b[8:]),
+			SndInfo: parseSndInfo(b[12:28]),
+			AssocID: AssocID(nativeEndian.Uint32(b[28:])),
+			Data:    tail(b, 32),
+		}
+	case SCTP_SHUTDOWN_EVENT:
+		if err := need(12); err != nil {
+			return nil, err
+		}
+		n.event = &Shutdown{
+			Type:    h.Type,
+			Flags:   h.Flags,
+			Length:  h.Length,
+			AssocID: AssocID(nativeEndian.Uint32(b[8:])),
+		}
+	case SCTP_ADAPTATION_INDICATION:
+		if err := need(16); err != nil {
+			return nil, err
+		}
+		n.event = &AdaptationIndication{
+			Type:       h.Type,
+			Flags:      h.Flags,
+			Length:     h.Length,
+			Indication: nativeEndian.Uint32(b[8:]),
+			AssocID:    AssocID(nativeEndian.Uint32(b[12:])),
+		}
+	case SCTP_PARTIAL_DELIVERY_EVENT:
+		if err := need(16); err != nil {
+			return nil, err
+		}
+		e := &PartialDelivery{
+			Type:       h.Type,
+			Flags:      h.Flags,
+			Length:     h.Length,
+			Indication: nativeEndian.Uint32(b[8:]),
+			AssocID:    AssocID(nativeEndian.Uint32(b[12:])),
+		}
+		if len(b) >= 24 {
+			e.StreamID = nativeEndian.Uint32(b[16:])
+			e.SequenceNumber = nativeEndian.Uint32(b[20:])
+		}
+		n.event = e
+	case SCTP_AUTHENTICATION_INDICATION:
+		if err := need(20); err != nil {
+			return nil, err
+		}
+		n.event = &Authentication{
+			Type:         h.Type,
+			Flags:        h.Flags,
+			Length:       h.Length,
+			KeyNumber:    nativeEndian.Uint16(b[8:]),
+			AltKeyNumber: nativeEndian.Uint16(b[10:]),
+			Indication:   nativeEndian.Uint32(b[12:]),
+			AssocID:      AssocID(nativeEndian.Uint32(b[16:])),
+		}
+	case SCTP_SENDER_DRY_EVENT:
+		if err := need(12); err != nil {
+			return nil, err
+		}
+		n.event = &SenderDry{
+			Type:    h.Type,
+			Flags:   h.Flags,
+			Length:  h.Length,
+			AssocID: AssocID(nativeEndian.Uint32(b[8:])),
+		}
+	case SCTP_STREAM_RESET_EVENT:
+		if err := need(12); err != nil {
+			return nil, err
+		}
+		e := &StreamResetEvent{
+			Type:    h.Type,
+			Flags:   h.Flags,
+			Length:  h.Length,
+			AssocID: AssocID(nativeEndian.Uint32(b[8:])),
+		}
+		for off := 12; off+2 <= len(b); off += 2 {
+			e.Streams = append(e.Streams, nativeEndian.Uint16(b[off:]))
+		}
+		n.event = e
+	case SCTP_ASSOC_RESET_EVENT:
+		if err := need(20); err != nil {
+			return nil, err
+		}
+		n.event = &AssocResetEvent{
+			Type:      h.Type,
+			Flags:     h.Flags,
+			Length:    h.Length,
+			AssocID:   AssocID(nativeEndian.Uint32(b[8:])),
+			LocalTSN:  nativeEndian.Uint32(b[12:]),
+			RemoteTSN: nativeEndian.Uint32(b[16:]),
+		}
+	case SCTP_STREAM_CHANGE_EVENT:
+		if err := need(16); err != nil {
+			return nil, err
+		}
+		n.event = &StreamChangeEvent{
+			Type:            h.Type,
+			Flags:           h.Flags,
+			Length:          h.Length,
+			AssocID:         AssocID(nativeEndian.Uint32(b[8:])),
+			InboundStreams:  nativeEndian.Uint16(b[12:]),
+			OutboundStreams: nativeEndian.Uint16(b[14:]),
+		}
+	}
+	return n, nil
+}
+
+// tail returns a copy of b from off on, or nil if there is nothing there.
+func tail(b []byte, off int) []byte {
+	if len(b) <= off {
+		return nil
+	}
+	return append([]byte(nil), b[off:]...)
+}
+
+// parseSndRcvInfo decodes the 32 bytes of a struct sctp_sndrcvinfo.
+func parseSndRcvInfo(b []byte) SndRcvInfo {
+	return SndRcvInfo{
+		Stream:  nativeEndian.Uint16(b[0:]),
+		SSN:     nativeEndian.Uint16(b[2:]),
+		Flags:   nativeEndian.Uint16(b[4:]),
+		PPID:    nativeEndian.Uint32(b[8:]),
+		Context: nativeEndian.Uint32(b[12:]),
+		TTL:     nativeEndian.Uint32(b[16:]),
+		TSN:     nativeEndian.Uint32(b[20:]),
+		CumTSN:  nativeEndian.Uint32(b[24:]),
+		AssocID: AssocID(nativeEndian.Uint32(b[28:])),
+	}
+}
+
+// parseSndInfo decodes the 16 bytes of a struct sctp_sndinfo.
+func parseSndInfo(b []byte) SndInfo {
+	return SndInfo{
+		Stream:  nativeEndian.Uint16(b[0:]),
+		Flags:   nativeEndian.Uint16(b[2:]),
+		PPID:    nativeEndian.Uint32(b[4:]),
+		Context: nativeEndian.Uint32(b[8:]),
+		AssocID: AssocID(nativeEndian.Uint32(b[12:])),
+	}
+}
+
+// notifier hands the notifications diverted from the read path over to
+// the channel returned by SCTPConn.Notifications.
+type notifier struct {
+	ch       chan *Notification
+	overflow NotificationOverflow
+	done     chan struct{}
+	dropped  uint64
+
+	mu      sync.Mutex
+	closed  bool
+	pending []*Notification
+
+	// amu guards adaptation, the adaptation layer indications announced
+	// by the peers of the live associations
+	amu        sync.Mutex
+	adaptation map[AssocID]uint32
+}
+
+func newNotifier(size int, overflow NotificationOverflow) *notifier {
+	return &notifier{
+		ch:       make(chan *Notification, size),
+		overflow: overflow,
+		done:     make(chan struct{}),
+	}
+}
+
+// deliver queues notif, which may be nil, behind the notifications still
+// pending and sends them in order. With NotificationBlock it waits for the
+// consumer until ctx is done or deadline passes; whatever could not be sent
+// by then is kept for the next call.
+func (n *notifier) deliver(ctx context.Context, deadline time.Time, notif *Notification) error {
+	return n.deliverWith(ctx, deadline, notif, n.overflow)
+}
+
+// offer acts like deliver under NotificationDrop, whatever the overflow
+// policy of n, for readers that must never wait for the consumer.
+func (n *notifier) offer(notif *Notification) error {
+	return n.deliverWith(context.Background(), time.Time{}, notif, NotificationDrop)
+}
+
+func (n *notifier) deliverWith(ctx context.Context, deadline time.Time, notif *Notification, overflow NotificationOverflow) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return net.ErrClosed
+	}
+	if notif != nil {
+		n.pending = append(n.pending, notif)
+	}
+	if len(n.pending) == 0 {
+		return nil
+	}
+
+	var expired <-chan time.Time
+	if overflow == NotificationBlock && !deadline.IsZero() {
+		t := time.NewTimer(time.Until(deadline))
+		defer t.Stop()
+		expired = t.C
+	}
+	for len(n.pending) > 0 {
+		if overflow == NotificationDrop {
+			select {
+			case n.ch <- n.pending[0]:
+			default:
+				atomic.AddUint64(&n.dropped, 1)
+			}
+		} else {
+			select {
+			case n.ch <- n.pending[0]:
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-expired:
+				return os.ErrDeadlineExceeded
+			case <-n.done:
+				return net.ErrClosed
+			}
+		}
+		n.pending[0] = nil
+		n.pending = n.pending[1:]
+	}
+	return nil
+}
+
+// track records the adaptation layer indication announced by notif and
+// forgets it once the association is gone.
+func (n *notifier) track(notif *Notification) {
+	n.amu.Lock()
+	defer n.amu.Unlock()
+	switch e := notif.event.(type) {
+	case *AdaptationIndication:
+		if n.adaptation == nil {
+			n.adaptation = make(map[AssocID]uint32)
+		}
+		n.adaptation[e.AssocID] = e.Indication
+	case *AssociationChange:
+		switch e.State {
+		case SCTP_COMM_LOST, SCTP_SHUTDOWN_COMP, SCTP_CANT_STR_ASSOC, SCTP_RESTART:
+			delete(n.adaptation, e.AssocID)
+		}
+	}
+}
+
+// peerAdaptation returns the adaptation layer indication of the peer of
+// the association. On a OneToOne socket, where assocID may be 0, any
+// association tracked is the one.
+func (n *notifier) peerAdaptation(assocID AssocID, oneToOne bool) (uint32, bool) {
+	n.amu.Lock()
+	defer n.amu.Unlock()
+	if ind, ok := n.adaptation[assocID]; ok {
+		return ind, true
+	}
+	if oneToOne && assocID == 0 {
+		for _, ind := range n.adaptation {
+			return ind, true
+		}
+	}
+	return 0, false
+}
+
+// close closes the channel, discarding pending notifications.
+func (n *notifier) close() {
+	close(n.done)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.closed = true
+	n.pending = nil
+	close(n.ch)
+}
@@ -1,12 +1,14 @@
 package sctp
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"sync"
-	"sync/atomic"
+	"time"
 	"unsafe"
 
 	syscall "golang.org/x/sys/unix"
@@ -32,22 +34,12 @@ func SCTPSocket(af int, mode SCTPSocketMode) (int, error) {
 }
 
 func SCTPGetSocketMode(fd int) (SCTPSocketMode, error) {
-	optname := syscall.SO_TYPE
-	optval := int(0)
-	optlen := unsafe.Sizeof(optname)
-	r0, _, err := syscall.Syscall6(syscall.SYS_GETSOCKOPT,
-		uintptr(fd),
-		syscall.SOL_SOCKET,
-		uintptr(optname),
-		uintptr(unsafe.Pointer(&optval)),
-		uintptr(optlen),
-		0)
-
-	if err != 0 {
+	socketType, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+	if err != nil {
 		return -1, err
 	}
 
-	switch r0 {
+	switch socketType {
 	case syscall.SOCK_STREAM:
 		return OneToOne, nil
 	case syscall.SOCK_SEQPACKET:
@@ -70,7 +62,10 @@ func SCTPGetInitOpts(fd int) (InitMsg, error) {
 	return options, err
 }
 
-func SCTPConnect(fd int, addr *SCTPAddr) (int, error) {
+// SCTPConnect starts setting up an association to addr and returns its ID.
+// The ID is also valid when the setup is still in progress, as reported by
+// EINPROGRESS on nonblocking sockets.
+func SCTPConnect(fd int, addr *SCTPAddr) (AssocID, error) {
 	buf := addr.ToRawSockAddrBuf()
 	param := GetAddrsOld{
 		AddrNum: int32(len(buf)),
@@ -78,13 +73,15 @@ func SCTPConnect(fd int, addr *SCTPAddr) (int, error) {
 	}
 	optlen := unsafe.Sizeof(param)
 	_, _, err := getsockopt(fd, SCTP_SOCKOPT_CONNECTX3, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
-	if err == nil {
-		return int(param.AssocID), nil
-	} else if err != syscall.ENOPROTOOPT {
+	switch err {
+	case nil, syscall.EINPROGRESS:
+		return param.AssocID, err
+	case syscall.ENOPROTOOPT:
+	default:
 		return 0, err
 	}
 	r0, _, err := setsockopt(fd, SCTP_SOCKOPT_CONNECTX, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
-	return int(r0), err
+	return AssocID(r0), err
 }
 
 func SCTPBind(fd int, addr *SCTPAddr, flags int) error {
@@ -112,23 +109,152 @@ func SCTPAccept(fd int) (int, error) {
 	return fd, err
 }
 
+// sctpCmsg encodes data as an IPPROTO_SCTP control message of type t,
+// padded so that further control messages can be appended.
+func sctpCmsg(t SCTPCmsgType, data []byte) []byte {
+	hdr := &syscall.Cmsghdr{
+		Level: syscall.IPPROTO_SCTP,
+		Type:  t.i32(),
+	}
+
+	// bitwidth of hdr.Len is platform-specific,
+	// so we use hdr.SetLen() rather than directly setting hdr.Len
+	hdr.SetLen(syscall.CmsgLen(len(data)))
+	buf := make([]byte, syscall.CmsgSpace(len(data)))
+	copy(buf, toBuf(hdr))
+	copy(buf[syscall.CmsgLen(0):], data)
+	return buf
+}
+
 func SCTPWrite(fd int, b []byte, info *SndRcvInfo) (int, error) {
 	var cbuf []byte
 	if info != nil {
-		cmsgBuf := toBuf(info)
-		hdr := &syscall.Cmsghdr{
-			Level: syscall.IPPROTO_SCTP,
-			Type:  SCTP_CMSG_SNDRCV.i32(),
-		}
+		cbuf = sctpCmsg(SCTP_CMSG_SNDRCV, toBuf(info))
+	}
+	return syscall.SendmsgN(fd, b, cbuf, nil, 0)
+}
 
-		// bitwidth of hdr.Len is platform-specific,
-		// so we use hdr.SetLen() rather than directly setting hdr.Len
-		hdr.SetLen(syscall.CmsgSpace(len(cmsgBuf)))
-		cbuf = append(toBuf(hdr), cmsgBuf...)
+// SCTPSendMsg sends b as one message, using the RFC 6458 SCTP_SNDINFO
+// control message to select the stream, PPID, flags and association.
+func SCTPSendMsg(fd int, b []byte, info *SndInfo) (int, error) {
+	var cbuf []byte
+	if info != nil {
+		cbuf = sctpCmsg(SCTP_CMSG_SNDINFO, toBuf(info))
 	}
 	return syscall.SendmsgN(fd, b, cbuf, nil, 0)
 }
 
+// SCTPSendMsgTo acts like SCTPSendMsg but sends b to the first address of
+// to. On a OneToMany socket, an association to to is set up on the fly if
+// there is none yet, with b carried along the handshake.
+func SCTPSendMsgTo(fd int, b []byte, info *SndInfo, to *SCTPAddr) (int, error) {
+	sa, err := to.sockaddr()
+	if err != nil {
+		return 0, err
+	}
+	var cbuf []byte
+	if info != nil {
+		cbuf = sctpCmsg(SCTP_CMSG_SNDINFO, toBuf(info))
+	}
+	return syscall.SendmsgN(fd, b, cbuf, sa, 0)
+}
+
+// SCTPRecvMsg receives a message into b and returns all SCTP control
+// messages that came with it.
+func SCTPRecvMsg(fd int, b []byte) (dataCount int, info *RecvMsgInfo, flags int, err error) {
+	dataCount, info, flags, _, err = SCTPRecvMsgFrom(fd, b)
+	return
+}
+
+// SCTPRecvMsgFrom acts like SCTPRecvMsg and also returns the peer address
+// the message came from.
+func SCTPRecvMsgFrom(fd int, b []byte) (dataCount int, info *RecvMsgInfo, flags int, from *SCTPAddr, err error) {
+
+	oobBuffer := make([]byte, 254)
+	oobCount := 0
+
+	var sa syscall.Sockaddr
+	dataCount, oobCount, flags, sa, err = syscall.Recvmsg(fd, b, oobBuffer, 0)
+
+	if err != nil {
+		return
+	}
+	from = sockaddrToSCTPAddr(sa)
+
+	if dataCount == 0 && oobCount == 0 {
+		err = io.EOF
+		return
+	}
+
+	var msgs []*OOBMessage
+	if msgs, err = SCTPParseOOBMessages(oobBuffer[:oobCount]); err != nil {
+		return
+	}
+	info = newRecvMsgInfo(msgs)
+	return
+}
+
+func newRecvMsgInfo(msgs []*OOBMessage) *RecvMsgInfo {
+	info := &RecvMsgInfo{}
+	for _, m := range msgs {
+		switch m.Type() {
+		case SCTP_CMSG_SNDRCV:
+			if len(m.Data) >= int(unsafe.Sizeof(SndRcvInfo{})) {
+				v := *m.GetSndRcvInfo()
+				info.SndRcvInfo = &v
+			}
+		case SCTP_CMSG_RCVINFO:
+			if len(m.Data) >= int(unsafe.Sizeof(RcvInfo{})) {
+				v := *m.GetRcvInfo()
+				info.RcvInfo = &v
+			}
+		case SCTP_CMSG_NXTINFO:
+			if len(m.Data) >= int(unsafe.Sizeof(NxtInfo{})) {
+				v := *m.GetNxtInfo()
+				info.NxtInfo = &v
+			}
+		}
+	}
+	return info
+}
+
+// sctpPeekMsg looks at the next message without consuming it and returns
+// its flags and the SCTP control messages that came with it.
+func sctpPeekMsg(fd int) (flags int, info *RecvMsgInfo, err error) {
+	var b [1]byte
+	oob := make([]byte, 254)
+	n, oobn, flags, _, err := syscall.Recvmsg(fd, b[:], oob, syscall.MSG_PEEK)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n == 0 && oobn == 0 && flags == 0 {
+		return 0, nil, io.EOF
+	}
+	msgs, err := SCTPParseOOBMessages(oob[:oobn])
+	if err != nil {
+		return 0, nil, err
+	}
+	return flags, newRecvMsgInfo(msgs), nil
+}
+
+func SCTPSetRecvRcvInfo(fd int, on bool) error {
+	return syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_RECVRCVINFO, boolint(on))
+}
+
+func SCTPGetRecvRcvInfo(fd int) (bool, error) {
+	on, err := syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_RECVRCVINFO)
+	return on != 0, err
+}
+
+func SCTPSetRecvNxtInfo(fd int, on bool) error {
+	return syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_RECVNXTINFO, boolint(on))
+}
+
+func SCTPGetRecvNxtInfo(fd int) (bool, error) {
+	on, err := syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_RECVNXTINFO)
+	return on != 0, err
+}
+
 func SCTPRead(fd int, b []byte) (dataCount int, oob *OOBMessage, flags int, err error) {
 
 	oobBuffer := make([]byte, 254)
@@ -152,20 +278,23 @@ func SCTPRead(fd int, b []byte) (dataCount int, oob *OOBMessage, flags int, err
 	return
 }
 
+// SCTPClose shuts the association down and closes fd. It is up to the
+// caller to make sure fd is closed only once and is not in use by other
+// goroutines; SCTPConn.Close takes care of both.
 func SCTPClose(fd int) error {
-	if fd > 0 {
-		fdq := int32(fd)
-		fd = int(atomic.SwapInt32(&fdq, -1))
-		if fd > 0 {
-			info := &SndRcvInfo{
-				Flags: SCTP_EOF,
-			}
-			SCTPWrite(fd, nil, info)
-			syscall.Shutdown(fd, syscall.SHUT_RDWR)
-			return syscall.Close(fd)
-		}
+	if fd < 0 {
+		return syscall.EBADF
 	}
-	return syscall.EBADF
+	sctpShutdown(fd)
+	return syscall.Close(fd)
+}
+
+func sctpShutdown(fd int) {
+	info := &SndRcvInfo{
+		Flags: SCTP_EOF,
+	}
+	SCTPWrite(fd, nil, info)
+	syscall.Shutdown(fd, syscall.SHUT_RDWR)
 }
 
 func SCTPSetNonblocking(fd int, nonblocking bool) error {
@@ -180,23 +309,23 @@ func SCTPGetNonblocking(fd int) (bool, error) {
 	return flags&syscall.O_NONBLOCK > 0, nil
 }
 
-func SCTPGetLocalAddr(fd int, stream uint16) (*SCTPAddr, error) {
-	return SCTPGetAddrs(fd, stream, SCTP_GET_LOCAL_ADDRS)
+func SCTPGetLocalAddr(fd int, assocID AssocID) (*SCTPAddr, error) {
+	return SCTPGetAddrs(fd, assocID, SCTP_GET_LOCAL_ADDRS)
 }
 
-func SCTPGetRemoteAddr(fd int, stream uint16) (*SCTPAddr, error) {
-	return SCTPGetAddrs(fd, stream, SCTP_GET_PEER_ADDRS)
+func SCTPGetRemoteAddr(fd int, assocID AssocID) (*SCTPAddr, error) {
+	return SCTPGetAddrs(fd, assocID, SCTP_GET_PEER_ADDRS)
 }
 
-func SCTPGetAddrs(fd int, id uint16, optname int) (*SCTPAddr, error) {
+func SCTPGetAddrs(fd int, assocID AssocID, optname int) (*SCTPAddr, error) {
 
 	type getaddrs struct {
-		assocId int32
+		assocId AssocID
 		addrNum uint32
 		addrs   [4096]byte
 	}
 	param := getaddrs{
-		assocId: int32(id),
+		assocId: assocID,
 	}
 	optlen := unsafe.Sizeof(param)
 	_, _, err := getsockopt(fd, uintptr(optname), uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
@@ -204,42 +333,94 @@ func SCTPGetAddrs(fd int, id uint16, optname int) (*SCTPAddr, error) {
 		return nil, err
 	}
 
-	ptr, n := unsafe.Pointer(&param.addrs), int(param.addrNum)
-
+	n := int(param.addrNum)
 	addr := &SCTPAddr{
-		IPAddrs: make([]net.IPAddr, n),
+		IPAddrs: make([]net.IPAddr, 0, n),
 	}
 
-	switch family := (*(*syscall.RawSockaddrAny)(ptr)).Addr.Family; family {
-	case syscall.AF_INET:
-		addr.Port = int(ntohs(uint16((*(*syscall.RawSockaddrInet4)(ptr)).Port)))
-		tmp := syscall.RawSockaddrInet4{}
-		size := unsafe.Sizeof(tmp)
-		for i := 0; i < n; i++ {
-			a := *(*syscall.RawSockaddrInet4)(unsafe.Pointer(
-				uintptr(ptr) + size*uintptr(i)))
-			addr.IPAddrs[i] = net.IPAddr{IP: a.Addr[:]}
+	// IPv4 and IPv6 addresses can be mixed, each taking the size of its
+	// own sockaddr
+	b := param.addrs[:]
+	for i := 0; i < n; i++ {
+		ip, port, err := parseSockaddr(b)
+		if err != nil {
+			return nil, err
 		}
-	case syscall.AF_INET6:
-		addr.Port = int(ntohs(uint16((*(*syscall.RawSockaddrInet4)(ptr)).Port)))
-		tmp := syscall.RawSockaddrInet6{}
-		size := unsafe.Sizeof(tmp)
-		for i := 0; i < n; i++ {
-			a := *(*syscall.RawSockaddrInet6)(unsafe.Pointer(
-				uintptr(ptr) + size*uintptr(i)))
-			var zone string
-			ifi, err := net.InterfaceByIndex(int(a.Scope_id))
-			if err == nil {
-				zone = ifi.Name
-			}
-			addr.IPAddrs[i] = net.IPAddr{IP: a.Addr[:], Zone: zone}
+		addr.IPAddrs = append(addr.IPAddrs, ip)
+		addr.Port = port
+		if len(ip.IP) == net.IPv4len {
+			b = b[syscall.SizeofSockaddrInet4:]
+		} else {
+			b = b[syscall.SizeofSockaddrInet6:]
 		}
-	default:
-		return nil, fmt.Errorf("unknown address family: %d", family)
 	}
 	return addr, nil
 }
 
+// SCTPGetPrimaryAddr returns the peer address the association sends to by
+// default.
+func SCTPGetPrimaryAddr(fd int, assocID AssocID) (*SCTPAddr, error) {
+	buf := make([]byte, 4+128)
+	nativeEndian.PutUint32(buf, uint32(assocID))
+	optlen := uintptr(len(buf))
+	_, _, err := getsockopt(fd, SCTP_PRIMARY_ADDR, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	ip, port, err := parseSockaddr(buf[4:])
+	if err != nil {
+		return nil, err
+	}
+	return &SCTPAddr{IPAddrs: []net.IPAddr{ip}, Port: port}, nil
+}
+
+// SCTPSetPrimaryAddr makes addr, one of the peer addresses of the
+// association, the one sent to by default.
+func SCTPSetPrimaryAddr(fd int, assocID AssocID, addr *SCTPAddr) error {
+	buf, err := sctpAssocAddr(assocID, addr, 4+128)
+	if err != nil {
+		return err
+	}
+	_, _, err = setsockopt(fd, SCTP_PRIMARY_ADDR, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return err
+}
+
+// SCTPGetPeerAddrInfo returns the state of the path to addr, one of the
+// peer addresses of the association.
+func SCTPGetPeerAddrInfo(fd int, assocID AssocID, addr *SCTPAddr) (*PeerAddrInfo, error) {
+	buf, err := sctpAssocAddr(assocID, addr, sizeofPeerAddrInfo)
+	if err != nil {
+		return nil, err
+	}
+	optlen := uintptr(len(buf))
+	_, _, err = getsockopt(fd, SCTP_GET_PEER_ADDR_INFO, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return parsePeerAddrInfo(buf)
+}
+
+// parsePeerAddrInfo decodes a packed struct sctp_paddrinfo.
+func parsePeerAddrInfo(b []byte) (*PeerAddrInfo, error) {
+	if len(b) < sizeofPeerAddrInfo {
+		return nil, fmt.Errorf("short sctp_paddrinfo: %d bytes", len(b))
+	}
+	ip, port, err := parseSockaddr(b[4:])
+	if err != nil {
+		return nil, err
+	}
+	return &PeerAddrInfo{
+		AssocID: AssocID(nativeEndian.Uint32(b)),
+		Addr:    ip,
+		Port:    port,
+		State:   PeerAddrState(nativeEndian.Uint32(b[132:])),
+		Cwnd:    nativeEndian.Uint32(b[136:]),
+		SRTT:    time.Duration(nativeEndian.Uint32(b[140:])) * time.Millisecond,
+		RTO:     time.Duration(nativeEndian.Uint32(b[144:])) * time.Millisecond,
+		MTU:     nativeEndian.Uint32(b[148:]),
+	}, nil
+}
+
 func SCTPGetDefaultSentParam(fd int) (*SndRcvInfo, error) {
 	info := &SndRcvInfo{}
 	optlen := unsafe.Sizeof(*info)
@@ -253,9 +434,30 @@ func SCTPSetDefaultSentParam(fd int, info *SndRcvInfo) error {
 	return err
 }
 
-func SCTPPeelOff(fd int, associd int32) (int, error) {
+// setHeartbeatInterval sets the default heartbeat interval of the endpoint.
+// A negative interval disables heartbeats, zero keeps the kernel default.
+func setHeartbeatInterval(fd int, interval time.Duration) error {
+	param := paddrParams{}
+	switch {
+	case interval > 0:
+		param.Flags = SPP_HB_ENABLE
+		param.HBInterval = uint32(interval / time.Millisecond)
+		if param.HBInterval == 0 {
+			param.HBInterval = 1
+		}
+	case interval < 0:
+		param.Flags = SPP_HB_DISABLE
+	default:
+		return nil
+	}
+	buf := toBuf(param)
+	_, _, err := setsockopt(fd, SCTP_PEER_ADDR_PARAMS, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return err
+}
+
+func SCTPPeelOff(fd int, associd AssocID) (int, error) {
 	type peeloffArg struct {
-		assocId int32
+		assocId AssocID
 		sd      int
 	}
 	param := peeloffArg{
@@ -274,7 +476,94 @@ func SCTPPeelOff(fd int, associd int32) (int, error) {
 
 }
 
+// SCTPSetEvents subscribes to exactly the notifications selected by flags,
+// a combination of SCTP_EVENT_* values. Events outside SCTP_EVENT_LEGACY
+// are set through SCTP_EVENT; turning them off is not an error on kernels
+// that do not know them.
 func SCTPSetEvents(fd, flags int) error {
+	if err := sctpSetLegacyEvents(fd, flags); err != nil {
+		return err
+	}
+	for t := SCTP_STREAM_RESET_EVENT; t <= SCTP_SEND_FAILED_EVENT; t++ {
+		on := flags&t.eventFlag() != 0
+		if err := SCTPSetEvent(fd, SCTP_FUTURE_ASSOC, t, on); err != nil && on {
+			return err
+		}
+	}
+	return nil
+}
+
+func SCTPGetEvents(fd int) (int, error) {
+	flags, err := sctpGetLegacyEvents(fd)
+	if err != nil {
+		return 0, err
+	}
+	for t := SCTP_STREAM_RESET_EVENT; t <= SCTP_SEND_FAILED_EVENT; t++ {
+		if on, err := SCTPGetEvent(fd, SCTP_FUTURE_ASSOC, t); err == nil && on {
+			flags |= t.eventFlag()
+		}
+	}
+	return flags, nil
+}
+
+// SCTPSetEvent turns notifications of type t on or off for one association
+// using SCTP_EVENT. On kernels without SCTP_EVENT, subscriptions for
+// SCTP_FUTURE_ASSOC fall back to the SCTP_EVENTS struct, which applies to
+// the whole socket.
+func SCTPSetEvent(fd int, assocID AssocID, t SCTPNotificationType, on bool) error {
+	param := sctpEvent{
+		AssocID: assocID,
+		Type:    uint16(t),
+		On:      uint8(boolint(on)),
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_EVENT, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	if err != syscall.ENOPROTOOPT || assocID != SCTP_FUTURE_ASSOC {
+		return err
+	}
+	flag := t.eventFlag()
+	if flag&SCTP_EVENT_LEGACY == 0 {
+		return err
+	}
+	flags, err := sctpGetLegacyEvents(fd)
+	if err != nil {
+		return err
+	}
+	if on {
+		flags |= flag
+	} else {
+		flags &^= flag
+	}
+	return sctpSetLegacyEvents(fd, flags)
+}
+
+// SCTPGetEvent reports whether notifications of type t are turned on for
+// an association, falling back to SCTP_EVENTS like SCTPSetEvent.
+func SCTPGetEvent(fd int, assocID AssocID, t SCTPNotificationType) (bool, error) {
+	param := sctpEvent{
+		AssocID: assocID,
+		Type:    uint16(t),
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_EVENT, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	if err == nil {
+		return param.On != 0, nil
+	}
+	if err != syscall.ENOPROTOOPT || assocID != SCTP_FUTURE_ASSOC {
+		return false, err
+	}
+	flag := t.eventFlag()
+	if flag&SCTP_EVENT_LEGACY == 0 {
+		return false, err
+	}
+	flags, err := sctpGetLegacyEvents(fd)
+	if err != nil {
+		return false, err
+	}
+	return flags&flag != 0, nil
+}
+
+func sctpSetLegacyEvents(fd, flags int) error {
 
 	var d, a, ad, sf, p, sh, pa, ada, au, se uint8
 	if flags&SCTP_EVENT_DATA_IO > 0 {
@@ -324,7 +613,7 @@ func SCTPSetEvents(fd, flags int) error {
 	return err
 }
 
-func SCTPGetEvents(fd int) (int, error) {
+func sctpGetLegacyEvents(fd int) (int, error) {
 	param := EventSubscribe{}
 	optlen := unsafe.Sizeof(param)
 	_, _, err := getsockopt(fd, SCTP_EVENTS, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
@@ -365,6 +654,874 @@ func SCTPGetEvents(fd int) (int, error) {
 	return flags, nil
 }
 
+// sctpSetAssocValue sets an option taking a struct sctp_assoc_value.
+func sctpSetAssocValue(fd, optname int, assocID AssocID, value uint32) error {
+	param := assocValue{
+		AssocID: assocID,
+		Value:   value,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, uintptr(optname), uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+// sctpGetAssocValue reads an option taking a struct sctp_assoc_value.
+func sctpGetAssocValue(fd, optname int, assocID AssocID) (uint32, error) {
+	param := assocValue{
+		AssocID: assocID,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, uintptr(optname), uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	return param.Value, err
+}
+
+// SCTPSendMsgPR acts like SCTPSendMsg, additionally passing pr in an
+// SCTP_PRINFO control message to select the PR-SCTP policy of the message.
+func SCTPSendMsgPR(fd int, b []byte, info *SndInfo, pr *PRInfo) (int, error) {
+	var cbuf []byte
+	if info != nil {
+		cbuf = sctpCmsg(SCTP_CMSG_SNDINFO, toBuf(info))
+	}
+	if pr != nil {
+		cbuf = append(cbuf, sctpCmsg(SCTP_CMSG_PRINFO, toBuf(pr))...)
+	}
+	return syscall.SendmsgN(fd, b, cbuf, nil, 0)
+}
+
+// SCTPSetPRSupported enables or disables the negotiation of PR-SCTP
+// (RFC 3758) for associations set up afterwards.
+func SCTPSetPRSupported(fd int, assocID AssocID, on bool) error {
+	return sctpSetAssocValue(fd, SCTP_PR_SUPPORTED, assocID, uint32(boolint(on)))
+}
+
+// SCTPGetPRSupported reports whether PR-SCTP is enabled on the endpoint
+// or, for an established association, whether the peer supports it.
+func SCTPGetPRSupported(fd int, assocID AssocID) (bool, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_PR_SUPPORTED, assocID)
+	return v != 0, err
+}
+
+// SCTPSetDefaultPRInfo sets the PR-SCTP policy applied to messages sent
+// without an SCTP_PRINFO control message.
+func SCTPSetDefaultPRInfo(fd int, assocID AssocID, info PRInfo) error {
+	param := defaultPRInfo{
+		AssocID: assocID,
+		Value:   info.Value,
+		Policy:  info.Policy,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_DEFAULT_PRINFO, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+func SCTPGetDefaultPRInfo(fd int, assocID AssocID) (PRInfo, error) {
+	param := defaultPRInfo{
+		AssocID: assocID,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_DEFAULT_PRINFO, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	return PRInfo{Policy: param.Policy, Value: param.Value}, err
+}
+
+// SCTPGetPRAssocStatus returns how many messages of an association were
+// abandoned under policy, or under any policy for SCTP_PR_SCTP_ALL.
+func SCTPGetPRAssocStatus(fd int, assocID AssocID, policy PRPolicy) (*PRStatus, error) {
+	return sctpGetPRStatus(fd, SCTP_PR_ASSOC_STATUS, assocID, 0, policy)
+}
+
+// SCTPGetPRStreamStatus is like SCTPGetPRAssocStatus for a single
+// outgoing stream.
+func SCTPGetPRStreamStatus(fd int, assocID AssocID, stream uint16, policy PRPolicy) (*PRStatus, error) {
+	return sctpGetPRStatus(fd, SCTP_PR_STREAM_STATUS, assocID, stream, policy)
+}
+
+func sctpGetPRStatus(fd, optname int, assocID AssocID, stream uint16, policy PRPolicy) (*PRStatus, error) {
+	status := &PRStatus{
+		AssocID: assocID,
+		Stream:  stream,
+		Policy:  policy,
+	}
+	optlen := unsafe.Sizeof(*status)
+	_, _, err := getsockopt(fd, uintptr(optname), uintptr(unsafe.Pointer(status)), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// SCTPSetReconfigSupported enables or disables the negotiation of stream
+// reconfiguration (RFC 6525) for associations set up afterwards.
+func SCTPSetReconfigSupported(fd int, assocID AssocID, on bool) error {
+	return sctpSetAssocValue(fd, SCTP_RECONFIG_SUPPORTED, assocID, uint32(boolint(on)))
+}
+
+func SCTPGetReconfigSupported(fd int, assocID AssocID) (bool, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_RECONFIG_SUPPORTED, assocID)
+	return v != 0, err
+}
+
+// SCTPSetEnableStreamReset selects the reconfiguration requests that may
+// be sent, a combination of SCTP_ENABLE_RESET_STREAM_REQ,
+// SCTP_ENABLE_RESET_ASSOC_REQ and SCTP_ENABLE_CHANGE_ASSOC_REQ.
+func SCTPSetEnableStreamReset(fd int, assocID AssocID, flags int) error {
+	return sctpSetAssocValue(fd, SCTP_ENABLE_STREAM_RESET, assocID, uint32(flags))
+}
+
+func SCTPGetEnableStreamReset(fd int, assocID AssocID) (int, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_ENABLE_STREAM_RESET, assocID)
+	return int(v), err
+}
+
+// SCTPResetStreams asks the peer to reset the incoming and/or outgoing
+// streams listed, as selected by SCTP_STREAM_RESET_INCOMING and
+// SCTP_STREAM_RESET_OUTGOING in flags. An empty list resets all streams.
+// The outcome is reported with SCTP_STREAM_RESET_EVENT.
+func SCTPResetStreams(fd int, assocID AssocID, flags int, streams []uint16) error {
+	if len(streams) > SCTP_MAX_STREAM {
+		return syscall.EINVAL
+	}
+	buf := make([]byte, 8+2*len(streams))
+	nativeEndian.PutUint32(buf[0:], uint32(assocID))
+	nativeEndian.PutUint16(buf[4:], uint16(flags))
+	nativeEndian.PutUint16(buf[6:], uint16(len(streams)))
+	for i, s := range streams {
+		nativeEndian.PutUint16(buf[8+2*i:], s)
+	}
+	_, _, err := setsockopt(fd, SCTP_RESET_STREAMS, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return err
+}
+
+// SCTPResetAssoc asks the peer to reset the TSNs of the association. The
+// outcome is reported with SCTP_ASSOC_RESET_EVENT.
+func SCTPResetAssoc(fd int, assocID AssocID) error {
+	optlen := unsafe.Sizeof(assocID)
+	_, _, err := setsockopt(fd, SCTP_RESET_ASSOC, uintptr(unsafe.Pointer(&assocID)), uintptr(optlen))
+	return err
+}
+
+// SCTPAddStreams adds in incoming and out outgoing streams to the
+// association. The outcome is reported with SCTP_STREAM_CHANGE_EVENT.
+func SCTPAddStreams(fd int, assocID AssocID, in, out uint16) error {
+	param := addStreams{
+		AssocID:  assocID,
+		InStrms:  in,
+		OutStrms: out,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_ADD_STREAMS, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+// SCTPSetAuthSupported enables or disables the negotiation of SCTP-AUTH
+// (RFC 4895) for associations set up afterwards.
+func SCTPSetAuthSupported(fd int, assocID AssocID, on bool) error {
+	return sctpSetAssocValue(fd, SCTP_AUTH_SUPPORTED, assocID, uint32(boolint(on)))
+}
+
+func SCTPGetAuthSupported(fd int, assocID AssocID) (bool, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_AUTH_SUPPORTED, assocID)
+	return v != 0, err
+}
+
+// SCTPAuthChunk requires chunks of the given type to be authenticated by
+// the peer. Chunks can be added but not removed.
+func SCTPAuthChunk(fd int, chunk uint8) error {
+	_, _, err := setsockopt(fd, SCTP_AUTH_CHUNK, uintptr(unsafe.Pointer(&chunk)), unsafe.Sizeof(chunk))
+	return err
+}
+
+// SCTPSetHMACIdent sets the HMAC algorithms offered to the peer, in order
+// of preference. SCTP_AUTH_HMAC_ID_SHA1 must be one of them.
+func SCTPSetHMACIdent(fd int, idents []uint16) error {
+	buf := make([]byte, 4+2*len(idents))
+	nativeEndian.PutUint32(buf[0:], uint32(len(idents)))
+	for i, id := range idents {
+		nativeEndian.PutUint16(buf[4+2*i:], id)
+	}
+	_, _, err := setsockopt(fd, SCTP_HMAC_IDENT, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return err
+}
+
+func SCTPGetHMACIdent(fd int) ([]uint16, error) {
+	buf := make([]byte, 4+2*16)
+	optlen := uintptr(len(buf))
+	_, _, err := getsockopt(fd, SCTP_HMAC_IDENT, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	n := int(nativeEndian.Uint32(buf))
+	if 4+2*n > int(optlen) {
+		return nil, syscall.EINVAL
+	}
+	idents := make([]uint16, n)
+	for i := range idents {
+		idents[i] = nativeEndian.Uint16(buf[4+2*i:])
+	}
+	return idents, nil
+}
+
+// SCTPSetAuthKey adds or replaces the shared key with the given number.
+// The key is only used once made active with SCTPSetActiveKey.
+func SCTPSetAuthKey(fd int, assocID AssocID, keyNumber uint16, key []byte) error {
+	if len(key) > 0xffff {
+		return syscall.EINVAL
+	}
+	buf := make([]byte, 8+len(key))
+	nativeEndian.PutUint32(buf[0:], uint32(assocID))
+	nativeEndian.PutUint16(buf[4:], keyNumber)
+	nativeEndian.PutUint16(buf[6:], uint16(len(key)))
+	copy(buf[8:], key)
+	_, _, err := setsockopt(fd, SCTP_AUTH_KEY, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	for i := range buf {
+		buf[i] = 0
+	}
+	return err
+}
+
+// SCTPSetActiveKey makes the shared key with the given number the one
+// used to authenticate outgoing chunks.
+func SCTPSetActiveKey(fd int, assocID AssocID, keyNumber uint16) error {
+	return sctpSetAuthKeyID(fd, SCTP_AUTH_ACTIVE_KEY, assocID, keyNumber)
+}
+
+func SCTPGetActiveKey(fd int, assocID AssocID) (uint16, error) {
+	param := authKeyID{
+		AssocID: assocID,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_AUTH_ACTIVE_KEY, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	return param.KeyNumber, err
+}
+
+// SCTPDeactivateKey stops using a shared key for outgoing chunks. Once
+// chunks still in flight no longer need it, an SCTP_AUTH_FREE_KEY
+// indication is sent and the key can be deleted.
+func SCTPDeactivateKey(fd int, assocID AssocID, keyNumber uint16) error {
+	return sctpSetAuthKeyID(fd, SCTP_AUTH_DEACTIVATE_KEY, assocID, keyNumber)
+}
+
+// SCTPDeleteKey removes a shared key that is no longer active.
+func SCTPDeleteKey(fd int, assocID AssocID, keyNumber uint16) error {
+	return sctpSetAuthKeyID(fd, SCTP_AUTH_DELETE_KEY, assocID, keyNumber)
+}
+
+func sctpSetAuthKeyID(fd, optname int, assocID AssocID, keyNumber uint16) error {
+	param := authKeyID{
+		AssocID:   assocID,
+		KeyNumber: keyNumber,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, uintptr(optname), uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+// SCTPGetPeerAuthChunks returns the chunk types the peer requires to be
+// authenticated.
+func SCTPGetPeerAuthChunks(fd int, assocID AssocID) ([]uint8, error) {
+	return sctpGetAuthChunks(fd, SCTP_PEER_AUTH_CHUNKS, assocID)
+}
+
+// SCTPGetLocalAuthChunks returns the chunk types required to be
+// authenticated by the local endpoint.
+func SCTPGetLocalAuthChunks(fd int, assocID AssocID) ([]uint8, error) {
+	return sctpGetAuthChunks(fd, SCTP_LOCAL_AUTH_CHUNKS, assocID)
+}
+
+func sctpGetAuthChunks(fd, optname int, assocID AssocID) ([]uint8, error) {
+	buf := make([]byte, 8+256)
+	nativeEndian.PutUint32(buf, uint32(assocID))
+	optlen := uintptr(len(buf))
+	_, _, err := getsockopt(fd, uintptr(optname), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	n := int(nativeEndian.Uint32(buf[4:]))
+	if 8+n > int(optlen) {
+		return nil, syscall.EINVAL
+	}
+	return append([]uint8(nil), buf[8:8+n]...), nil
+}
+
+// SCTPSetStreamScheduler selects the scheduler sharing the association
+// between outgoing streams.
+func SCTPSetStreamScheduler(fd int, assocID AssocID, scheduler SCTPStreamScheduler) error {
+	return sctpSetAssocValue(fd, SCTP_STREAM_SCHEDULER, assocID, uint32(scheduler))
+}
+
+func SCTPGetStreamScheduler(fd int, assocID AssocID) (SCTPStreamScheduler, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_STREAM_SCHEDULER, assocID)
+	return SCTPStreamScheduler(v), err
+}
+
+// SCTPSetStreamSchedulerValue sets the value of an outgoing stream for the
+// current scheduler: its priority for SCTP_SS_PRIO and its weight for
+// SCTP_SS_WFQ.
+func SCTPSetStreamSchedulerValue(fd int, assocID AssocID, stream, value uint16) error {
+	param := streamValue{
+		AssocID:     assocID,
+		StreamID:    stream,
+		StreamValue: value,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_STREAM_SCHEDULER_VALUE, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+func SCTPGetStreamSchedulerValue(fd int, assocID AssocID, stream uint16) (uint16, error) {
+	param := streamValue{
+		AssocID:  assocID,
+		StreamID: stream,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_STREAM_SCHEDULER_VALUE, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	return param.StreamValue, err
+}
+
+// SCTPSetInterleavingSupported enables or disables the negotiation of user
+// message interleaving (RFC 8260, I-DATA chunks) for associations set up
+// afterwards. The kernel requires full fragment interleaving to be on
+// first, so enabling it also sets SCTP_FRAGMENT_INTERLEAVE to 2.
+func SCTPSetInterleavingSupported(fd int, assocID AssocID, on bool) error {
+	if on {
+		if err := syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_FRAGMENT_INTERLEAVE, 2); err != nil {
+			return err
+		}
+	}
+	return sctpSetAssocValue(fd, SCTP_INTERLEAVING_SUPPORTED, assocID, uint32(boolint(on)))
+}
+
+func SCTPGetInterleavingSupported(fd int, assocID AssocID) (bool, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_INTERLEAVING_SUPPORTED, assocID)
+	return v != 0, err
+}
+
+// sctpAssocAddr encodes the association ID followed by the first address
+// of addr in a sockaddr_storage, as in the packed structs sctp_prim,
+// sctp_setpeerprim and sctp_paddrinfo.
+func sctpAssocAddr(assocID AssocID, addr *SCTPAddr, size int) ([]byte, error) {
+	if addr == nil || len(addr.IPAddrs) == 0 {
+		return nil, &net.AddrError{Err: "missing address"}
+	}
+	st := sockaddrStorage(addr)
+	buf := make([]byte, size)
+	nativeEndian.PutUint32(buf, uint32(assocID))
+	copy(buf[4:], st[:])
+	return buf, nil
+}
+
+// SCTPSetPeerPrimaryAddr asks the peer to use addr, one of the local
+// addresses of the association, as its primary destination. This needs
+// ASCONF to be supported by both ends.
+func SCTPSetPeerPrimaryAddr(fd int, assocID AssocID, addr *SCTPAddr) error {
+	buf, err := sctpAssocAddr(assocID, addr, 4+128)
+	if err != nil {
+		return err
+	}
+	_, _, err = setsockopt(fd, SCTP_SET_PEER_PRIMARY_ADDR, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return err
+}
+
+// SCTPSetAutoASCONF turns the automatic announcement of local address
+// changes to the peers on or off. It only applies to sockets bound to the
+// wildcard address.
+func SCTPSetAutoASCONF(fd int, on bool) error {
+	return syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_AUTO_ASCONF, boolint(on))
+}
+
+func SCTPGetAutoASCONF(fd int) (bool, error) {
+	on, err := syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_AUTO_ASCONF)
+	return on != 0, err
+}
+
+// SCTPSetASCONFSupported enables or disables the negotiation of dynamic
+// address reconfiguration (RFC 5061) for associations set up afterwards.
+func SCTPSetASCONFSupported(fd int, assocID AssocID, on bool) error {
+	return sctpSetAssocValue(fd, SCTP_ASCONF_SUPPORTED, assocID, uint32(boolint(on)))
+}
+
+func SCTPGetASCONFSupported(fd int, assocID AssocID) (bool, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_ASCONF_SUPPORTED, assocID)
+	return v != 0, err
+}
+
+// SCTPGetPeerAddrParams returns the parameters of the path to raddr or,
+// if raddr is nil, the defaults of the association or endpoint.
+func SCTPGetPeerAddrParams(fd int, assocID AssocID, raddr *SCTPAddr) (*PeerAddrParams, error) {
+	param := paddrParams{
+		AssocID: assocID,
+		Address: sockaddrStorage(raddr),
+	}
+	buf := toBuf(param)
+	optlen := uintptr(len(buf))
+	_, _, err := getsockopt(fd, SCTP_PEER_ADDR_PARAMS, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	if err := binary.Read(bytes.NewReader(buf), nativeEndian, &param); err != nil {
+		return nil, err
+	}
+	return &PeerAddrParams{
+		Addr:          raddr,
+		HBInterval:    time.Duration(param.HBInterval) * time.Millisecond,
+		PathMaxRxt:    param.PathMaxRxt,
+		PathMTU:       param.PathMTU,
+		SackDelay:     time.Duration(param.SackDelay) * time.Millisecond,
+		Flags:         param.Flags,
+		IPv6FlowLabel: param.IPv6FlowLabel,
+		DSCP:          param.DSCP,
+	}, nil
+}
+
+// SCTPSetPeerAddrParams applies p to the path to p.Addr or, if p.Addr is
+// nil, to all paths of the association or endpoint. Zero fields are left
+// unchanged, the SPP_* flags in p.Flags turn features on and off.
+func SCTPSetPeerAddrParams(fd int, assocID AssocID, p *PeerAddrParams) error {
+	param := paddrParams{
+		AssocID:       assocID,
+		Address:       sockaddrStorage(p.Addr),
+		HBInterval:    uint32(p.HBInterval / time.Millisecond),
+		PathMaxRxt:    p.PathMaxRxt,
+		PathMTU:       p.PathMTU,
+		SackDelay:     uint32(p.SackDelay / time.Millisecond),
+		Flags:         p.Flags,
+		IPv6FlowLabel: p.IPv6FlowLabel,
+		DSCP:          p.DSCP,
+	}
+	buf := toBuf(param)
+	_, _, err := setsockopt(fd, SCTP_PEER_ADDR_PARAMS, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return err
+}
+
+// SCTPGetPeerAddrThresholds returns the failure thresholds of the path to
+// raddr or, if raddr is nil, the defaults of the association or endpoint.
+func SCTPGetPeerAddrThresholds(fd int, assocID AssocID, raddr *SCTPAddr) (*PeerAddrThresholds, error) {
+	param := paddrThlds{
+		AssocID: assocID,
+		Address: sockaddrStorage(raddr),
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_PEER_ADDR_THLDS, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return &PeerAddrThresholds{
+		Addr:        raddr,
+		PathMaxRxt:  param.PathMaxRxt,
+		PFThreshold: param.PathPFThld,
+	}, nil
+}
+
+// SCTPSetPeerAddrThresholds sets the failure thresholds of the path to
+// t.Addr or, if t.Addr is nil, of all paths of the association or
+// endpoint.
+func SCTPSetPeerAddrThresholds(fd int, assocID AssocID, t *PeerAddrThresholds) error {
+	param := paddrThlds{
+		AssocID:    assocID,
+		Address:    sockaddrStorage(t.Addr),
+		PathMaxRxt: t.PathMaxRxt,
+		PathPFThld: t.PFThreshold,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_PEER_ADDR_THLDS, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+// SCTPGetRTOInfo returns the retransmission timeout bounds of the
+// association.
+func SCTPGetRTOInfo(fd int, assocID AssocID) (*RTOInfo, error) {
+	param := rtoInfo{AssocID: assocID}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_RTOINFO, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return &RTOInfo{
+		Initial: time.Duration(param.Initial) * time.Millisecond,
+		Max:     time.Duration(param.Max) * time.Millisecond,
+		Min:     time.Duration(param.Min) * time.Millisecond,
+	}, nil
+}
+
+func SCTPSetRTOInfo(fd int, assocID AssocID, info *RTOInfo) error {
+	param := rtoInfo{
+		AssocID: assocID,
+		Initial: uint32(info.Initial / time.Millisecond),
+		Max:     uint32(info.Max / time.Millisecond),
+		Min:     uint32(info.Min / time.Millisecond),
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_RTOINFO, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+// SCTPGetAssocParams returns the parameters of the association.
+func SCTPGetAssocParams(fd int, assocID AssocID) (*AssocParams, error) {
+	param := assocParams{AssocID: assocID}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_ASSOCINFO, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return &AssocParams{
+		MaxRxt:           param.MaxRxt,
+		PeerDestinations: param.PeerDestinations,
+		PeerRwnd:         param.PeerRwnd,
+		LocalRwnd:        param.LocalRwnd,
+		CookieLife:       time.Duration(param.CookieLife) * time.Millisecond,
+	}, nil
+}
+
+func SCTPSetAssocParams(fd int, assocID AssocID, p *AssocParams) error {
+	param := assocParams{
+		AssocID:    assocID,
+		MaxRxt:     p.MaxRxt,
+		CookieLife: uint32(p.CookieLife / time.Millisecond),
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_ASSOCINFO, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+// SCTPGetSackInfo returns the delayed SACK settings of the association.
+func SCTPGetSackInfo(fd int, assocID AssocID) (*SackInfo, error) {
+	param := sackInfo{AssocID: assocID}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := getsockopt(fd, SCTP_DELAYED_SACK, uintptr(unsafe.Pointer(&param)), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return &SackInfo{
+		Delay: time.Duration(param.Delay) * time.Millisecond,
+		Freq:  param.Freq,
+	}, nil
+}
+
+func SCTPSetSackInfo(fd int, assocID AssocID, info *SackInfo) error {
+	param := sackInfo{
+		AssocID: assocID,
+		Delay:   uint32(info.Delay / time.Millisecond),
+		Freq:    info.Freq,
+	}
+	optlen := unsafe.Sizeof(param)
+	_, _, err := setsockopt(fd, SCTP_DELAYED_SACK, uintptr(unsafe.Pointer(&param)), uintptr(optlen))
+	return err
+}
+
+// SCTPSetMaxBurst limits the number of packets sent at once on the
+// association. Zero disables the limit.
+func SCTPSetMaxBurst(fd int, assocID AssocID, burst uint32) error {
+	return sctpSetAssocValue(fd, SCTP_MAX_BURST, assocID, burst)
+}
+
+func SCTPGetMaxBurst(fd int, assocID AssocID) (uint32, error) {
+	return sctpGetAssocValue(fd, SCTP_MAX_BURST, assocID)
+}
+
+// setAssocTuning applies the association settings of a dialer or listen
+// config to the endpoint. Nil settings and a zero maxBurst keep the kernel
+// defaults, a negative maxBurst disables the burst limit.
+func setAssocTuning(fd int, rto *RTOInfo, assoc *AssocParams, sack *SackInfo, maxBurst int) error {
+	if rto != nil {
+		if err := SCTPSetRTOInfo(fd, 0, rto); err != nil {
+			return err
+		}
+	}
+	if assoc != nil {
+		if err := SCTPSetAssocParams(fd, 0, assoc); err != nil {
+			return err
+		}
+	}
+	if sack != nil {
+		if err := SCTPSetSackInfo(fd, 0, sack); err != nil {
+			return err
+		}
+	}
+	switch {
+	case maxBurst > 0:
+		return SCTPSetMaxBurst(fd, 0, uint32(maxBurst))
+	case maxBurst < 0:
+		return SCTPSetMaxBurst(fd, 0, 0)
+	}
+	return nil
+}
+
+// SCTPGetStatus returns the state of the association. Its primary path is
+// reported only once the association is established.
+func SCTPGetStatus(fd int, assocID AssocID) (*AssocStatus, error) {
+	buf := make([]byte, 24+sizeofPeerAddrInfo)
+	nativeEndian.PutUint32(buf, uint32(assocID))
+	optlen := uintptr(len(buf))
+	_, _, err := getsockopt(fd, SCTP_STATUS, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return parseAssocStatus(buf)
+}
+
+// parseAssocStatus decodes a struct sctp_status.
+func parseAssocStatus(b []byte) (*AssocStatus, error) {
+	if len(b) < 24+sizeofPeerAddrInfo {
+		return nil, fmt.Errorf("short sctp_status: %d bytes", len(b))
+	}
+	status := &AssocStatus{
+		AssocID:            AssocID(nativeEndian.Uint32(b)),
+		State:              AssocState(nativeEndian.Uint32(b[4:])),
+		Rwnd:               nativeEndian.Uint32(b[8:]),
+		UnackedData:        nativeEndian.Uint16(b[12:]),
+		PendingData:        nativeEndian.Uint16(b[14:]),
+		InboundStreams:     nativeEndian.Uint16(b[16:]),
+		OutboundStreams:    nativeEndian.Uint16(b[18:]),
+		FragmentationPoint: nativeEndian.Uint32(b[20:]),
+	}
+	// the primary path is left zeroed before the association is up
+	if nativeEndian.Uint16(b[28:]) != 0 {
+		primary, err := parsePeerAddrInfo(b[24:])
+		if err != nil {
+			return nil, err
+		}
+		status.Primary = *primary
+	}
+	return status, nil
+}
+
+// SCTPGetAssocStats returns the statistics of the association. Reading
+// them resets MaxRTO, the highest RTO observed since the previous read.
+func SCTPGetAssocStats(fd int, assocID AssocID) (*AssocStats, error) {
+	buf := make([]byte, sizeofAssocStats)
+	nativeEndian.PutUint32(buf, uint32(assocID))
+	optlen := uintptr(len(buf))
+	_, _, err := getsockopt(fd, SCTP_GET_ASSOC_STATS, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+	if err != nil {
+		return nil, err
+	}
+	return parseAssocStats(buf)
+}
+
+// parseAssocStats decodes a struct sctp_assoc_stats, whose counters follow
+// the sockaddr_storage of the address the highest RTO was observed on.
+func parseAssocStats(b []byte) (*AssocStats, error) {
+	if len(b) < sizeofAssocStats {
+		return nil, fmt.Errorf("short sctp_assoc_stats: %d bytes", len(b))
+	}
+	counter := func(i int) uint64 {
+		return nativeEndian.Uint64(b[136+8*i:])
+	}
+	stats := &AssocStats{
+		AssocID:            AssocID(nativeEndian.Uint32(b)),
+		MaxRTO:             time.Duration(counter(0)) * time.Millisecond,
+		InSacks:            counter(1),
+		OutSacks:           counter(2),
+		OutPackets:         counter(3),
+		InPackets:          counter(4),
+		RetransChunks:      counter(5),
+		OutOfSeqTSNs:       counter(6),
+		DupChunks:          counter(7),
+		GapAcks:            counter(8),
+		OutUnorderedChunks: counter(9),
+		InUnorderedChunks:  counter(10),
+		OutOrderedChunks:   counter(11),
+		InOrderedChunks:    counter(12),
+		OutCtrlChunks:      counter(13),
+		InCtrlChunks:       counter(14),
+	}
+	if nativeEndian.Uint16(b[8:]) != 0 {
+		ip, port, err := parseSockaddr(b[8:136])
+		if err != nil {
+			return nil, err
+		}
+		stats.MaxRTOAddr = &SCTPAddr{IPAddrs: []net.IPAddr{ip}, Port: port}
+	}
+	return stats, nil
+}
+
+// SCTPSetNoDelay turns off the bundling delay of small messages, the
+// SCTP counterpart of TCP_NODELAY.
+func SCTPSetNoDelay(fd int, on bool) error {
+	return syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_NODELAY, boolint(on))
+}
+
+func SCTPGetNoDelay(fd int) (bool, error) {
+	on, err := syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_NODELAY)
+	return on != 0, err
+}
+
+// SCTPSetMaxSeg sets the largest DATA chunk payload sent on the
+// association. Zero lets the kernel follow the path MTU.
+func SCTPSetMaxSeg(fd int, assocID AssocID, size uint32) error {
+	return sctpSetAssocValue(fd, SCTP_MAXSEG, assocID, size)
+}
+
+func SCTPGetMaxSeg(fd int, assocID AssocID) (uint32, error) {
+	return sctpGetAssocValue(fd, SCTP_MAXSEG, assocID)
+}
+
+// SCTPSetDisableFragments stops the kernel from fragmenting messages; a
+// message that does not fit in one chunk then fails with EMSGSIZE.
+func SCTPSetDisableFragments(fd int, on bool) error {
+	return syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_DISABLE_FRAGMENTS, boolint(on))
+}
+
+func SCTPGetDisableFragments(fd int) (bool, error) {
+	on, err := syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_DISABLE_FRAGMENTS)
+	return on != 0, err
+}
+
+// SCTPSetFragmentInterleave sets how the parts of partially delivered
+// messages may be interleaved on reads: 0 not at all, 1 across
+// associations, 2 across associations and streams.
+func SCTPSetFragmentInterleave(fd int, level int) error {
+	return syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_FRAGMENT_INTERLEAVE, level)
+}
+
+func SCTPGetFragmentInterleave(fd int) (int, error) {
+	return syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_FRAGMENT_INTERLEAVE)
+}
+
+// SCTPSetPartialDeliveryPoint sets the size of the received part of a
+// message from which the kernel starts handing it to the application.
+func SCTPSetPartialDeliveryPoint(fd int, size uint32) error {
+	return syscall.SetsockoptInt(fd, SOL_SCTP, SCTP_PARTIAL_DELIVERY_POINT, int(size))
+}
+
+func SCTPGetPartialDeliveryPoint(fd int) (uint32, error) {
+	size, err := syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_PARTIAL_DELIVERY_POINT)
+	return uint32(size), err
+}
+
+// SCTPSetECNSupported enables or disables the negotiation of Explicit
+// Congestion Notification for associations set up afterwards.
+func SCTPSetECNSupported(fd int, assocID AssocID, on bool) error {
+	return sctpSetAssocValue(fd, SCTP_ECN_SUPPORTED, assocID, uint32(boolint(on)))
+}
+
+func SCTPGetECNSupported(fd int, assocID AssocID) (bool, error) {
+	v, err := sctpGetAssocValue(fd, SCTP_ECN_SUPPORTED, assocID)
+	return v != 0, err
+}
+
+// SCTPSetAdaptationLayer sets the adaptation layer indication sent to
+// peers in INIT and INIT-ACK. Zero sends none.
+func SCTPSetAdaptationLayer(fd int, ind uint32) error {
+	optlen := unsafe.Sizeof(ind)
+	_, _, err := setsockopt(fd, SCTP_ADAPTATION_LAYER, uintptr(unsafe.Pointer(&ind)), uintptr(optlen))
+	return err
+}
+
+func SCTPGetAdaptationLayer(fd int) (uint32, error) {
+	var ind uint32
+	optlen := unsafe.Sizeof(ind)
+	_, _, err := getsockopt(fd, SCTP_ADAPTATION_LAYER, uintptr(unsafe.Pointer(&ind)), uintptr(unsafe.Pointer(&optlen)))
+	return ind, err
+}
+
+// SCTPSetExtensions selects the extensions advertised by the endpoint for
+// the associations set up afterwards. SCTP-AUTH is set before ASCONF,
+// whose chunks the kernel only authenticates when AUTH is already on.
+// A nil ext leaves the kernel defaults in place.
+func SCTPSetExtensions(fd int, ext *Extensions) error {
+	if ext == nil {
+		return nil
+	}
+	for _, set := range []struct {
+		f  func(fd int, assocID AssocID, on bool) error
+		on bool
+	}{
+		{SCTPSetECNSupported, ext.ECN},
+		{SCTPSetPRSupported, ext.PR},
+		{SCTPSetReconfigSupported, ext.Reconfig},
+		{SCTPSetAuthSupported, ext.Auth},
+		{SCTPSetASCONFSupported, ext.ASCONF},
+		{SCTPSetInterleavingSupported, ext.Interleaving},
+	} {
+		if err := set.f(fd, SCTP_FUTURE_ASSOC, set.on); err != nil {
+			return err
+		}
+	}
+	return SCTPSetAdaptationLayer(fd, ext.AdaptationLayer)
+}
+
+// SCTPGetExtensions returns the extensions negotiated on the association
+// or, for SCTP_FUTURE_ASSOC, those advertised by the endpoint.
+// PeerAdaptationLayer is left nil: the peer's indication is only known
+// from SCTP_ADAPTATION_INDICATION.
+func SCTPGetExtensions(fd int, assocID AssocID) (*Extensions, error) {
+	ext := &Extensions{}
+	for _, get := range []struct {
+		f  func(fd int, assocID AssocID) (bool, error)
+		on *bool
+	}{
+		{SCTPGetECNSupported, &ext.ECN},
+		{SCTPGetPRSupported, &ext.PR},
+		{SCTPGetReconfigSupported, &ext.Reconfig},
+		{SCTPGetAuthSupported, &ext.Auth},
+		{SCTPGetASCONFSupported, &ext.ASCONF},
+		{SCTPGetInterleavingSupported, &ext.Interleaving},
+	} {
+		on, err := get.f(fd, assocID)
+		if err != nil {
+			return nil, err
+		}
+		*get.on = on
+	}
+	ind, err := SCTPGetAdaptationLayer(fd)
+	if err != nil {
+		return nil, err
+	}
+	ext.AdaptationLayer = ind
+	return ext, nil
+}
+
+// SCTPGetAssocNumber returns the number of associations of a OneToMany
+// socket.
+func SCTPGetAssocNumber(fd int) (int, error) {
+	return syscall.GetsockoptInt(fd, SOL_SCTP, SCTP_GET_ASSOC_NUMBER)
+}
+
+// SCTPGetAssocIDList returns the IDs of the associations of a OneToMany
+// socket.
+func SCTPGetAssocIDList(fd int) ([]AssocID, error) {
+	n, err := SCTPGetAssocNumber(fd)
+	if err != nil {
+		return nil, err
+	}
+	// leave room for associations set up in between, and more so on
+	// every retry; EINVAL can also mean the option is not supported, so
+	// give up after a few
+	spare := 8
+	for try := 0; ; try++ {
+		buf := make([]byte, 4+4*(n+spare))
+		optlen := uintptr(len(buf))
+		_, _, err = getsockopt(fd, SCTP_GET_ASSOC_ID_LIST, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&optlen)))
+		if err == syscall.EINVAL && try < 2 {
+			spare *= 8
+			if n, err = SCTPGetAssocNumber(fd); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseAssocIDList(buf)
+	}
+}
+
+// parseAssocIDList decodes a struct sctp_assoc_ids.
+func parseAssocIDList(b []byte) ([]AssocID, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("short sctp_assoc_ids: %d bytes", len(b))
+	}
+	n := int(nativeEndian.Uint32(b))
+	if len(b) < 4+4*n {
+		return nil, fmt.Errorf("sctp_assoc_ids of %d bytes holds %d IDs", len(b), n)
+	}
+	ids := make([]AssocID, n)
+	for i := range ids {
+		ids[i] = AssocID(nativeEndian.Uint32(b[4+4*i:]))
+	}
+	return ids, nil
+}
+
 func setsockopt(fd int, optname, optval, optlen uintptr) (uintptr, uintptr, error) {
 	// FIXME: syscall.SYS_SETSOCKOPT is undefined on 386
 	r0, r1, errno := syscall.Syscall6(syscall.SYS_SETSOCKOPT,
@@ -422,8 +1579,21 @@ func SCTPParseOOB(b []byte) (*OOBMessage, error) {
 	return nil, nil
 }
 
-func SCTPParseNotification(b []byte) (*Notification, error) {
-	return &Notification{Data: b}, nil
+// SCTPParseOOBMessages returns every SCTP control message found in b, in
+// the order they were delivered.
+func SCTPParseOOBMessages(b []byte) ([]*OOBMessage, error) {
+	msgs, err := syscall.ParseSocketControlMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	var sctpMsgs []*OOBMessage
+	for _, msg := range msgs {
+		m := &OOBMessage{msg}
+		if m.IsSCTP() {
+			sctpMsgs = append(sctpMsgs, m)
+		}
+	}
+	return sctpMsgs, nil
 }
 
 //from https://github.com/golang/go
@@ -0,0 +1,33 @@
+package sctp
+
+import (
+	stdsyscall "syscall"
+)
+
+// rawConn implements syscall.RawConn on top of an sctpFD. Read and Write
+// wait in the runtime poller, and none of the methods can observe the
+// descriptor after it has been closed.
+type rawConn struct {
+	fd *sctpFD
+}
+
+func (c *rawConn) Control(f func(uintptr)) error {
+	return c.fd.closeErr(c.fd.rc.Control(f))
+}
+
+func (c *rawConn) Read(f func(uintptr) bool) error {
+	return c.fd.closeErr(c.fd.rc.Read(f))
+}
+
+func (c *rawConn) Write(f func(uintptr) bool) error {
+	return c.fd.closeErr(c.fd.rc.Write(f))
+}
+
+// SyscallConn returns a raw network connection. This implements the
+// syscall.Conn interface.
+//
+// The descriptor passed to the callbacks is only valid for the duration of
+// the call and must not be closed or retained.
+func (c *SCTPConn) SyscallConn() (stdsyscall.RawConn, error) {
+	return &rawConn{fd: c.fd}, nil
+}
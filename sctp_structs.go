@@ -7,8 +7,11 @@ package sctp
 */
 import "C"
 import (
-	syscall "golang.org/x/sys/unix"
+	"net"
+	"time"
 	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
 )
 
 type EventSubscribe struct {
@@ -24,6 +27,14 @@ type EventSubscribe struct {
 	SenderDry       uint8
 }
 
+// sctpEvent is struct sctp_event, used with SCTP_EVENT.
+type sctpEvent struct {
+	AssocID AssocID
+	Type    uint16
+	On      uint8
+	_       uint8
+}
+
 type InitMsg struct {
 	NumOstreams    uint16
 	MaxInstreams   uint16
@@ -47,7 +58,19 @@ type SndRcvInfo struct {
 	TTL     uint32
 	TSN     uint32
 	CumTSN  uint32
-	AssocID int32
+	AssocID AssocID
+}
+
+type RcvInfo struct {
+	Stream  uint16
+	SSN     uint16
+	Flags   uint16
+	_       uint16
+	PPID    uint32
+	TSN     uint32
+	CumTSN  uint32
+	Context uint32
+	AssocID AssocID
 }
 
 type NxtInfo struct {
@@ -55,7 +78,7 @@ type NxtInfo struct {
 	Flags   uint16
 	PPID    uint32
 	Length  uint32
-	AssocID int32
+	AssocID AssocID
 }
 
 type SndInfo struct {
@@ -63,138 +86,257 @@ type SndInfo struct {
 	Flags   uint16
 	PPID    uint32
 	Context uint32
-	AssocID int32
-}
-
-type GetAddrsOld struct {
-	AssocID int32
-	AddrNum int32
-	Addrs   uintptr
-}
-
-type NotificationHeader struct {
-	Type   SCTPNotificationType
-	Flags  uint16
-	Length uint32
-}
-
-type Notification struct {
-	Data []byte
-}
-
-func (n *Notification) Header() *NotificationHeader {
-	return (*NotificationHeader)(unsafe.Pointer(&n.Data[0]))
-}
-
-func (n *Notification) Type() SCTPNotificationType {
-	return n.Header().Type
-}
-
-func (n *Notification) GetAssociationChange() *AssociationChange {
-	return (*AssociationChange)(unsafe.Pointer(&n.Data[0]))
-}
-
-func (n *Notification) GetPeerAddrChange() *PeerAddrChange {
-	return (*PeerAddrChange)(unsafe.Pointer(&n.Data[0]))
-}
-
-func (n *Notification) GetRemoteError() *RemoteError {
-	return (*RemoteError)(unsafe.Pointer(&n.Data[0]))
-}
-
-func (n *Notification) GetSendFailed() *SendFailed {
-	return (*SendFailed)(unsafe.Pointer(&n.Data[0]))
+	AssocID AssocID
 }
 
-func (n *Notification) GetAdaptationIndication() *AdaptationIndication {
-	return (*AdaptationIndication)(unsafe.Pointer(&n.Data[0]))
+// assocValue is struct sctp_assoc_value, shared by the options that hold a
+// single value per association.
+type assocValue struct {
+	AssocID AssocID
+	Value   uint32
 }
 
-func (n *Notification) GetPartialDelivery() *PartialDelivery {
-	return (*PartialDelivery)(unsafe.Pointer(&n.Data[0]))
+// addStreams is struct sctp_add_streams, used with SCTP_ADD_STREAMS.
+type addStreams struct {
+	AssocID  AssocID
+	InStrms  uint16
+	OutStrms uint16
 }
 
-func (n *Notification) GetAuthentication() *Authentication {
-	return (*Authentication)(unsafe.Pointer(&n.Data[0]))
+// streamValue is struct sctp_stream_value, used with
+// SCTP_STREAM_SCHEDULER_VALUE.
+type streamValue struct {
+	AssocID     AssocID
+	StreamID    uint16
+	StreamValue uint16
 }
 
-func (n *Notification) GetSenderDry() *SenderDry {
-	return (*SenderDry)(unsafe.Pointer(&n.Data[0]))
+// authKeyID is struct sctp_authkeyid, used by the options selecting a
+// shared key.
+type authKeyID struct {
+	AssocID   AssocID
+	KeyNumber uint16
+	_         uint16
 }
 
-//type AssociationChange C.struct_sctp_assoc_change
-
-type AssociationChange struct {
-	Type            SCTPNotificationType
-	Flags           uint16
-	Length          uint32
-	State           SCTPState
-	Error           uint16
-	OutboundStreams uint16
-	InboundStreams  uint16
-	AssocID         int32
-	Info            []byte
-}
-
-type PeerAddrChange struct {
-	Type    SCTPNotificationType
-	Length  uint32
-	Addr    C.struct_sockaddr_storage
-	State   PeerChangeState
-	Error   uint32
-	AssocID int32
-}
-
-type RemoteError struct {
-	Type    SCTPNotificationType
-	Flags   uint16
-	Length  uint32
-	Error   uint16
-	AssocID int32
-	Info    []byte
+// PRInfo is struct sctp_prinfo. Value is the lifetime in milliseconds for
+// SCTP_PR_SCTP_TTL, the number of retransmissions for SCTP_PR_SCTP_RTX and
+// the priority for SCTP_PR_SCTP_PRIO, where lower values are dropped first.
+type PRInfo struct {
+	Policy PRPolicy
+	_      uint16
+	Value  uint32
 }
 
-type SendFailed struct {
-	Type    SCTPNotificationType
-	Flags   uint16
-	Length  uint32
-	Error   uint16
-	SndInfo SndInfo
-	AssocID int32
-	Data    []byte
-}
-
-type AdaptationIndication struct {
-	Type       SCTPNotificationType
-	Flags      uint16
-	Length     uint32
-	Indication uint32
-	AssocID    int32
-}
-
-type PartialDelivery struct {
-	Type           SCTPNotificationType
-	Flags          uint16
-	Length         uint32
-	Indication     uint32
-	StreamID       uint32
-	SequenceNumber uint32
+// defaultPRInfo is struct sctp_default_prinfo, used with
+// SCTP_DEFAULT_PRINFO.
+type defaultPRInfo struct {
+	AssocID AssocID
+	Value   uint32
+	Policy  PRPolicy
+	_       uint16
 }
 
-type Authentication struct {
-	Type       SCTPNotificationType
-	Flags      uint16
-	Length     uint32
-	KeyNumber  uint16
-	Indication uint32
-	AssocID    int32
+// PRStatus is struct sctp_prstatus, the number of messages abandoned by
+// PR-SCTP before and after they were first sent.
+type PRStatus struct {
+	AssocID         AssocID
+	Stream          uint16
+	Policy          PRPolicy
+	AbandonedUnsent uint64
+	AbandonedSent   uint64
+}
+
+// PeerAddrInfo describes the path to one peer address, as reported by
+// SCTP_GET_PEER_ADDR_INFO.
+type PeerAddrInfo struct {
+	AssocID AssocID
+	Addr    net.IPAddr
+	Port    int
+	State   PeerAddrState
+	Cwnd    uint32
+	SRTT    time.Duration
+	RTO     time.Duration
+	MTU     uint32
+}
+
+// RTOInfo holds the retransmission timeout bounds of SCTP_RTOINFO. Zero
+// fields are left unchanged when setting.
+type RTOInfo struct {
+	Initial time.Duration
+	Max     time.Duration
+	Min     time.Duration
+}
+
+type rtoInfo struct {
+	AssocID AssocID
+	Initial uint32
+	Max     uint32
+	Min     uint32
+}
+
+// AssocParams holds the association parameters of SCTP_ASSOCINFO. Only
+// MaxRxt and CookieLife can be set, zero values leave them unchanged; the
+// other fields are reported by the kernel.
+type AssocParams struct {
+	MaxRxt           uint16
+	PeerDestinations uint16
+	PeerRwnd         uint32
+	LocalRwnd        uint32
+	CookieLife       time.Duration
+}
+
+type assocParams struct {
+	AssocID          AssocID
+	MaxRxt           uint16
+	PeerDestinations uint16
+	PeerRwnd         uint32
+	LocalRwnd        uint32
+	CookieLife       uint32
+}
+
+// SackInfo holds the delayed SACK settings of SCTP_DELAYED_SACK: the
+// maximum time a SACK is delayed and the number of packets received
+// before one is sent anyway. A Freq of 1 disables delayed SACKs.
+type SackInfo struct {
+	Delay time.Duration
+	Freq  uint32
+}
+
+type sackInfo struct {
+	AssocID AssocID
+	Delay   uint32
+	Freq    uint32
+}
+
+// Extensions selects the optional SCTP extensions an endpoint advertises
+// in INIT and INIT-ACK, or reports those negotiated on an association.
+// AdaptationLayer is the adaptation layer indication; zero means none is
+// sent.
+type Extensions struct {
+	ECN             bool
+	ASCONF          bool
+	Reconfig        bool
+	PR              bool
+	Auth            bool
+	Interleaving    bool
+	AdaptationLayer uint32
+
+	// PeerAdaptationLayer is only set in negotiated reports, when the
+	// peer announced an adaptation layer indication.
+	PeerAdaptationLayer *uint32
+}
+
+// sizeofPeerAddrInfo is the size of the packed struct sctp_paddrinfo.
+const sizeofPeerAddrInfo = 4 + 128 + 20
+
+// AssocStatus is a snapshot of an association as reported by SCTP_STATUS.
+type AssocStatus struct {
+	AssocID            AssocID
+	State              AssocState
+	Rwnd               uint32
+	UnackedData        uint16
+	PendingData        uint16
+	InboundStreams     uint16
+	OutboundStreams    uint16
+	FragmentationPoint uint32
+	Primary            PeerAddrInfo
+}
+
+// sizeofAssocStats is the size of struct sctp_assoc_stats: the association
+// ID padded to 8 bytes, a sockaddr_storage and 15 counters.
+const sizeofAssocStats = 8 + 128 + 15*8
+
+// AssocStats holds the counters of SCTP_GET_ASSOC_STATS. MaxRTO is the
+// highest RTO observed since the previous read, on path MaxRTOAddr.
+type AssocStats struct {
+	AssocID            AssocID
+	MaxRTO             time.Duration
+	MaxRTOAddr         *SCTPAddr
+	InSacks            uint64
+	OutSacks           uint64
+	OutPackets         uint64
+	InPackets          uint64
+	RetransChunks      uint64
+	OutOfSeqTSNs       uint64
+	DupChunks          uint64
+	GapAcks            uint64
+	OutUnorderedChunks uint64
+	InUnorderedChunks  uint64
+	OutOrderedChunks   uint64
+	InOrderedChunks    uint64
+	OutCtrlChunks      uint64
+	InCtrlChunks       uint64
+}
+
+// paddrParams mirrors the packed struct sctp_paddrparams used by
+// SCTP_PEER_ADDR_PARAMS. It must be encoded with toBuf, which does not
+// insert any padding between fields.
+type paddrParams struct {
+	AssocID       AssocID
+	Address       [128]byte
+	HBInterval    uint32
+	PathMaxRxt    uint16
+	PathMTU       uint32
+	SackDelay     uint32
+	Flags         uint32
+	IPv6FlowLabel uint32
+	DSCP          uint8
+	_             uint8
+}
+
+// PeerAddrParams holds the parameters of SCTP_PEER_ADDR_PARAMS. Addr
+// selects a single path; when nil, the parameters are the defaults of the
+// association or endpoint. Flags is a combination of the SPP_* values,
+// which also tell whether IPv6FlowLabel and DSCP are set.
+type PeerAddrParams struct {
+	Addr          *SCTPAddr
+	HBInterval    time.Duration
+	PathMaxRxt    uint16
+	PathMTU       uint32
+	SackDelay     time.Duration
+	Flags         uint32
+	IPv6FlowLabel uint32
+	DSCP          uint8
+}
+
+// paddrThlds mirrors struct sctp_paddrthlds used by SCTP_PEER_ADDR_THLDS.
+// Unlike sctp_paddrparams it is not packed: the sockaddr_storage is 8-byte
+// aligned, and so is the size of the struct.
+type paddrThlds struct {
+	AssocID    AssocID
+	_          [4]byte
+	Address    [128]byte
+	PathMaxRxt uint16
+	PathPFThld uint16
+	_          [4]byte
+}
+
+// PeerAddrThresholds holds the number of retransmissions after which a
+// path is considered failed (PathMaxRxt) and potentially failed
+// (PFThreshold, RFC 7829). Addr selects a single path as in
+// PeerAddrParams.
+type PeerAddrThresholds struct {
+	Addr        *SCTPAddr
+	PathMaxRxt  uint16
+	PFThreshold uint16
+}
+
+// RecvMsgInfo holds the ancillary data received along with a message.
+// Each field is nil unless the corresponding control message was enabled
+// and delivered: SndRcvInfo by SCTP_EVENT_DATA_IO, RcvInfo by
+// SetRecvRcvInfo and NxtInfo by SetRecvNxtInfo. NxtInfo describes the
+// message that will be returned by the next read, if one is queued.
+type RecvMsgInfo struct {
+	SndRcvInfo *SndRcvInfo
+	RcvInfo    *RcvInfo
+	NxtInfo    *NxtInfo
 }
 
-type SenderDry struct {
-	Type    SCTPNotificationType
-	Flags   uint16
-	Length  uint32
-	AssocID int32
+type GetAddrsOld struct {
+	AssocID AssocID
+	AddrNum int32
+	Addrs   uintptr
 }
 
 type OOBMessage struct {
@@ -220,3 +362,7 @@ func (o *OOBMessage) GetSndInfo() *SndInfo {
 func (o *OOBMessage) GetNxtInfo() *NxtInfo {
 	return (*NxtInfo)(unsafe.Pointer(&o.Data[0]))
 }
+
+func (o *OOBMessage) GetRcvInfo() *RcvInfo {
+	return (*RcvInfo)(unsafe.Pointer(&o.Data[0]))
+}
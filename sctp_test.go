@@ -2,16 +2,20 @@ package sctp
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net"
+	"os"
 	"reflect"
 	"runtime"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unsafe"
 
 	syscall "golang.org/x/sys/unix"
 )
@@ -88,6 +92,839 @@ func TestResolveSCTPAddr(t *testing.T) {
 	}
 }
 
+func TestSCTPParseOOBMessages(t *testing.T) {
+	rcv := RcvInfo{Stream: 3, SSN: 7, PPID: 42, TSN: 1000, AssocID: 5}
+	nxt := NxtInfo{Stream: 4, PPID: 43, Length: 128, AssocID: 5}
+	b := append(sctpCmsg(SCTP_CMSG_RCVINFO, toBuf(rcv)), sctpCmsg(SCTP_CMSG_NXTINFO, toBuf(nxt))...)
+
+	msgs, err := SCTPParseOOBMessages(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d control messages, expected 2", len(msgs))
+	}
+
+	info := newRecvMsgInfo(msgs)
+	if info.RcvInfo == nil || *info.RcvInfo != rcv {
+		t.Errorf("got RcvInfo %+v, expected %+v", info.RcvInfo, rcv)
+	}
+	if info.NxtInfo == nil || *info.NxtInfo != nxt {
+		t.Errorf("got NxtInfo %+v, expected %+v", info.NxtInfo, nxt)
+	}
+	if info.SndRcvInfo != nil {
+		t.Errorf("got unexpected SndRcvInfo %+v", info.SndRcvInfo)
+	}
+}
+
+func TestSCTPParseNotification(t *testing.T) {
+	withLength := func(b []byte) []byte {
+		nativeEndian.PutUint32(b[4:], uint32(len(b)))
+		return b
+	}
+
+	type assocChange struct {
+		Type, Flags                     uint16
+		Length                          uint32
+		State, Error, Outbound, Inbound uint16
+		AssocID                         int32
+		Info                            [4]byte
+	}
+	b := withLength(toBuf(assocChange{Type: uint16(SCTP_ASSOC_CHANGE), State: uint16(SCTP_COMM_LOST), Outbound: 10, Inbound: 5, AssocID: 7, Info: [4]byte{1, 2, 3, 4}}))
+	notif, err := SCTPParseNotification(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac := notif.GetAssociationChange()
+	if ac == nil || ac.State != SCTP_COMM_LOST || ac.OutboundStreams != 10 || ac.InboundStreams != 5 || ac.AssocID != 7 || !bytes.Equal(ac.Info, []byte{1, 2, 3, 4}) {
+		t.Errorf("got %+v", ac)
+	}
+	if notif.GetPeerAddrChange() != nil || notif.AssocID() != 7 {
+		t.Errorf("association change decoded as another type")
+	}
+
+	type paddrChange struct {
+		Type, Flags           uint16
+		Length                uint32
+		Addr                  [128]byte
+		State, Error, AssocID int32
+	}
+	pc := paddrChange{Type: uint16(SCTP_PEER_ADDR_CHANGE), State: SCTP_ADDR_UNREACHABLE, AssocID: 3}
+	copy(pc.Addr[:], toBuf(syscall.RawSockaddrInet4{Family: syscall.AF_INET, Port: htons(5000), Addr: [4]byte{192, 0, 2, 1}}))
+	notif, err = SCTPParseNotification(withLength(toBuf(pc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := notif.GetPeerAddrChange(); e == nil || !e.Addr.IP.Equal(net.IPv4(192, 0, 2, 1)) || e.Port != 5000 || e.State != SCTP_ADDR_UNREACHABLE || e.AssocID != 3 {
+		t.Errorf("got %+v", e)
+	}
+
+	type sendFailed struct {
+		Type, Flags uint16
+		Length      uint32
+		Error       uint32
+		Info        SndRcvInfo
+		AssocID     int32
+		Data        [3]byte
+	}
+	info := SndRcvInfo{Stream: 2, PPID: 9, AssocID: 4}
+	notif, err = SCTPParseNotification(withLength(toBuf(sendFailed{Type: uint16(SCTP_SEND_FAILED), Error: 1, Info: info, AssocID: 4, Data: [3]byte{'a', 'b', 'c'}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := notif.GetSendFailed(); e == nil || e.SndRcvInfo != info || e.AssocID != 4 || string(e.Data) != "abc" {
+		t.Errorf("got %+v", e)
+	}
+
+	// kernels before 4.18 do not report the stream and sequence number
+	type pdapi struct {
+		Type, Flags uint16
+		Length      uint32
+		Indication  uint32
+		AssocID     int32
+	}
+	notif, err = SCTPParseNotification(withLength(toBuf(pdapi{Type: uint16(SCTP_PARTIAL_DELIVERY_EVENT), AssocID: 6})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := notif.GetPartialDelivery(); e == nil || e.AssocID != 6 {
+		t.Errorf("got %+v", e)
+	}
+
+	unknown := withLength(toBuf(struct {
+		Type, Flags uint16
+		Length      uint32
+	}{Type: 0xffff}))
+	if notif, err = SCTPParseNotification(unknown); err != nil {
+		t.Errorf("unknown notification: %v", err)
+	} else if notif.Type().String() == "" || notif.AssocID() != 0 {
+		t.Errorf("unknown notification decoded as %v", notif.Type())
+	}
+
+	for _, b := range [][]byte{
+		nil,
+		b[:4],
+		b[:len(b)-1],
+		withLength(toBuf(struct {
+			Type, Flags uint16
+			Length      uint32
+			State       uint16
+		}{Type: uint16(SCTP_ASSOC_CHANGE)})),
+	} {
+		if _, err := SCTPParseNotification(b); !errors.Is(err, ErrTruncatedNotification) {
+			t.Errorf("%d bytes: got %v, expected ErrTruncatedNotification", len(b), err)
+		}
+	}
+}
+
+func TestParseStreamReconfigNotifications(t *testing.T) {
+	withLength := func(b []byte) []byte {
+		nativeEndian.PutUint32(b[4:], uint32(len(b)))
+		return b
+	}
+
+	notif, err := SCTPParseNotification(withLength(toBuf(struct {
+		Type, Flags uint16
+		Length      uint32
+		AssocID     int32
+		Streams     [3]uint16
+	}{Type: uint16(SCTP_STREAM_RESET_EVENT), Flags: SCTP_STREAM_RESET_OUTGOING_SSN, AssocID: 2, Streams: [3]uint16{1, 4, 9}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := notif.GetStreamReset(); e == nil || e.Flags != SCTP_STREAM_RESET_OUTGOING_SSN || e.AssocID != 2 || !reflect.DeepEqual(e.Streams, []uint16{1, 4, 9}) {
+		t.Errorf("got %+v", e)
+	}
+
+	notif, err = SCTPParseNotification(withLength(toBuf(struct {
+		Type, Flags         uint16
+		Length              uint32
+		AssocID             int32
+		LocalTSN, RemoteTSN uint32
+	}{Type: uint16(SCTP_ASSOC_RESET_EVENT), AssocID: 3, LocalTSN: 100, RemoteTSN: 200})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := notif.GetAssocReset(); e == nil || e.AssocID != 3 || e.LocalTSN != 100 || e.RemoteTSN != 200 {
+		t.Errorf("got %+v", e)
+	}
+
+	notif, err = SCTPParseNotification(withLength(toBuf(struct {
+		Type, Flags uint16
+		Length      uint32
+		AssocID     int32
+		In, Out     uint16
+	}{Type: uint16(SCTP_STREAM_CHANGE_EVENT), Flags: SCTP_STREAM_CHANGE_DENIED, AssocID: 4, In: 10, Out: 20})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := notif.GetStreamChange(); e == nil || e.Flags != SCTP_STREAM_CHANGE_DENIED || e.InboundStreams != 10 || e.OutboundStreams != 20 || notif.AssocID() != 4 {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestKeyRotatorSchedule(t *testing.T) {
+	r := &KeyRotator{Interval: time.Hour}
+	start := time.Unix(0, 0).Add(1000 * time.Hour)
+
+	if n := r.keyNumber(start); n != 1000 {
+		t.Errorf("got key number %d, expected 1000", n)
+	}
+	if n := r.keyNumber(start.Add(59 * time.Minute)); n != 1000 {
+		t.Errorf("got key number %d within the same interval", n)
+	}
+	if n := r.keyNumber(start.Add(time.Hour)); n != 1001 {
+		t.Errorf("got key number %d, expected 1001", n)
+	}
+	if d := r.untilNext(start.Add(15 * time.Minute)); d != 45*time.Minute {
+		t.Errorf("next rotation in %v, expected 45m", d)
+	}
+
+	if err := r.Start(nil); err == nil {
+		t.Error("started without a key function")
+	}
+	r.Stop()
+
+	// notifications of other types are ignored
+	r.HandleNotification(&Notification{})
+
+	// as are peers using a key that is not the pending one
+	r.active, r.pending = 1000, 1001
+	r.HandleNotification(&Notification{event: &Authentication{Indication: SCTP_AUTH_NEW_KEY, KeyNumber: 1000}})
+	r.HandleNotification(&Notification{event: &Authentication{Indication: SCTP_AUTH_NEW_KEY, KeyNumber: 999}})
+}
+
+func TestSCTPAssocAddr(t *testing.T) {
+	addr := &SCTPAddr{
+		IPAddrs: []net.IPAddr{{IP: net.ParseIP("2001:db8::1")}, {IP: net.ParseIP("2001:db8::2")}},
+		Port:    3868,
+	}
+	buf, err := sctpAssocAddr(7, addr, 4+128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != 132 || int32(nativeEndian.Uint32(buf)) != 7 {
+		t.Fatalf("got %d bytes for association %d", len(buf), int32(nativeEndian.Uint32(buf)))
+	}
+	ip, port, err := parseSockaddr(buf[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.IP.Equal(addr.IPAddrs[0].IP) || port != 3868 {
+		t.Errorf("got %v port %d", ip, port)
+	}
+
+	if _, err := sctpAssocAddr(7, &SCTPAddr{Port: 3868}, 4+128); err == nil {
+		t.Error("encoded an empty address")
+	}
+}
+
+func TestSCTPNetworkFamily(t *testing.T) {
+	v4 := &SCTPAddr{AddressFamily: SCTP4}
+	v6 := &SCTPAddr{AddressFamily: SCTP6}
+	v6only := &SCTPAddr{AddressFamily: SCTP6Only}
+
+	// SCTPAddrFamily keeps treating SCTP6 as IPv6-only
+	if family, ipv6only := SCTPAddrFamily(v6, v6); family != syscall.AF_INET6 || !ipv6only {
+		t.Errorf("SCTPAddrFamily(SCTP6, SCTP6) = %d, %v", family, ipv6only)
+	}
+	if family, ipv6only := SCTPAddrFamily(nil, v6); family != syscall.AF_INET || ipv6only {
+		t.Errorf("SCTPAddrFamily(nil, SCTP6) = %d, %v", family, ipv6only)
+	}
+
+	for _, c := range []struct {
+		network      string
+		laddr, raddr *SCTPAddr
+		family       int
+		ipv6only     bool
+	}{
+		{"sctp", nil, v4, syscall.AF_INET, false},
+		{"sctp", v6, nil, syscall.AF_INET6, false},
+		{"sctp", nil, v6only, syscall.AF_INET6, true},
+		{"sctp4", nil, nil, syscall.AF_INET, false},
+		{"sctp6", nil, nil, syscall.AF_INET6, true},
+	} {
+		family, ipv6only, err := sctpNetworkFamily(c.network, c.laddr, c.raddr)
+		if err != nil || family != c.family || ipv6only != c.ipv6only {
+			t.Errorf("%s %v %v: got %d, %v, %v", c.network, c.laddr, c.raddr, family, ipv6only, err)
+		}
+	}
+	if _, _, err := sctpNetworkFamily("udp", nil, nil); err == nil {
+		t.Error("unknown network accepted")
+	}
+}
+
+func TestSCTPAddrSockaddr(t *testing.T) {
+	for _, addr := range []*SCTPAddr{
+		{IPAddrs: []net.IPAddr{{IP: net.IPv4(192, 0, 2, 1)}, {IP: net.IPv4(192, 0, 2, 2)}}, Port: 3868},
+		{IPAddrs: []net.IPAddr{{IP: net.ParseIP("2001:db8::1")}}, Port: 5060},
+	} {
+		sa, err := addr.sockaddr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		back := sockaddrToSCTPAddr(sa)
+		if back == nil || len(back.IPAddrs) != 1 || !back.IPAddrs[0].IP.Equal(addr.IPAddrs[0].IP) || back.Port != addr.Port {
+			t.Errorf("%v came back as %v", addr, back)
+		}
+	}
+	if _, err := (&SCTPAddr{Port: 3868}).sockaddr(); err == nil {
+		t.Error("converted an empty address")
+	}
+
+	var _ net.PacketConn = (*SCTPListener)(nil)
+	ln := &SCTPListener{socketMode: OneToMany}
+	if _, err := ln.WriteTo(nil, &net.UDPAddr{}); err == nil {
+		t.Error("wrote to a UDP address")
+	}
+}
+
+func TestParseAssocIDList(t *testing.T) {
+	b := make([]byte, 4+4*4)
+	nativeEndian.PutUint32(b, 3)
+	for i, id := range []AssocID{1, 5, 9} {
+		nativeEndian.PutUint32(b[4+4*i:], uint32(id))
+	}
+	ids, err := parseAssocIDList(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 5 || ids[2] != 9 {
+		t.Errorf("got %v", ids)
+	}
+	nativeEndian.PutUint32(b, 6)
+	if _, err := parseAssocIDList(b); err == nil {
+		t.Error("parsed more IDs than the buffer holds")
+	}
+}
+
+func TestParseAssocStatus(t *testing.T) {
+	raddr := &SCTPAddr{IPAddrs: []net.IPAddr{{IP: net.IPv4(192, 0, 2, 1)}}, Port: 3868}
+	b := make([]byte, 24+sizeofPeerAddrInfo)
+	nativeEndian.PutUint32(b, 3)
+	nativeEndian.PutUint32(b[4:], uint32(SCTP_ESTABLISHED))
+	nativeEndian.PutUint32(b[8:], 65536)
+	nativeEndian.PutUint16(b[12:], 2)
+	nativeEndian.PutUint16(b[16:], 10)
+	nativeEndian.PutUint16(b[18:], 5)
+	nativeEndian.PutUint32(b[20:], 1452)
+	st := sockaddrStorage(raddr)
+	copy(b[28:], st[:])
+	nativeEndian.PutUint32(b[24+132:], uint32(SCTP_ACTIVE))
+	nativeEndian.PutUint32(b[24+144:], 3000)
+	status, err := parseAssocStatus(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.AssocID != 3 || status.State != SCTP_ESTABLISHED || status.Rwnd != 65536 || status.UnackedData != 2 ||
+		status.InboundStreams != 10 || status.OutboundStreams != 5 || status.FragmentationPoint != 1452 {
+		t.Errorf("got %+v", status)
+	}
+	if !status.Primary.Addr.IP.Equal(raddr.IPAddrs[0].IP) || status.Primary.Port != 3868 ||
+		status.Primary.State != SCTP_ACTIVE || status.Primary.RTO != 3*time.Second {
+		t.Errorf("got primary path %+v", status.Primary)
+	}
+
+	b = make([]byte, sizeofAssocStats)
+	nativeEndian.PutUint32(b, 3)
+	copy(b[8:], st[:])
+	nativeEndian.PutUint64(b[136:], 1200)
+	nativeEndian.PutUint64(b[136+5*8:], 17)
+	nativeEndian.PutUint64(b[136+14*8:], 42)
+	stats, err := parseAssocStats(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.AssocID != 3 || stats.MaxRTO != 1200*time.Millisecond || stats.RetransChunks != 17 || stats.InCtrlChunks != 42 {
+		t.Errorf("got %+v", stats)
+	}
+	if stats.MaxRTOAddr == nil || stats.MaxRTOAddr.String() != "192.0.2.1:3868" {
+		t.Errorf("got max RTO address %v", stats.MaxRTOAddr)
+	}
+	if _, err := parseAssocStats(b[:100]); err == nil {
+		t.Error("parsed truncated stats")
+	}
+}
+
+func TestPrimaryPathPolicyIgnores(t *testing.T) {
+	p := &PrimaryPathPolicy{preferred: net.IPv4(192, 0, 2, 1)}
+	p.ErrorHandler = func(assocID AssocID, err error) {
+		t.Errorf("association %d: %v", assocID, err)
+	}
+	// none of these may touch the connection, which is nil here
+	for _, e := range []*PeerAddrChange{
+		{Addr: net.IPAddr{IP: net.IPv4(192, 0, 2, 2)}, State: SCTP_ADDR_AVAILABLE},
+		{Addr: net.IPAddr{IP: net.IPv4(192, 0, 2, 1)}, State: SCTP_ADDR_UNREACHABLE},
+	} {
+		p.HandleNotification(&Notification{event: e})
+	}
+	p.HandleNotification(&Notification{event: &SenderDry{}})
+
+	if s := PeerAddrState(42).String(); s != "PeerAddrState(42)" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestSetExtensionsNil(t *testing.T) {
+	c, _ := socketpairConn(t)
+	// nil keeps the kernel defaults, so no option is set on the socket
+	if err := c.SetExtensions(nil); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestNotifierOffer(t *testing.T) {
+	n := newNotifier(1, NotificationBlock)
+	// offer never waits for the consumer, whatever the overflow policy
+	for i := 0; i < 2; i++ {
+		if err := n.offer(&Notification{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dropped := atomic.LoadUint64(&n.dropped); dropped != 1 {
+		t.Errorf("got %d dropped", dropped)
+	}
+	if len(n.ch) != 1 {
+		t.Errorf("got %d queued", len(n.ch))
+	}
+}
+
+func TestNotifierAdaptation(t *testing.T) {
+	n := newNotifier(1, NotificationDrop)
+	if _, ok := n.peerAdaptation(0, true); ok {
+		t.Fatal("adaptation indication before any notification")
+	}
+	n.track(&Notification{event: &AdaptationIndication{Indication: 0x1234, AssocID: 5}})
+	for _, c := range []struct {
+		assocID  AssocID
+		oneToOne bool
+		ok       bool
+	}{
+		{5, false, true},
+		{0, true, true},
+		{0, false, false},
+		{6, false, false},
+	} {
+		ind, ok := n.peerAdaptation(c.assocID, c.oneToOne)
+		if ok != c.ok || (ok && ind != 0x1234) {
+			t.Errorf("association %d: got %#x, %v", c.assocID, ind, ok)
+		}
+	}
+	n.track(&Notification{event: &AssociationChange{State: SCTP_COMM_LOST, AssocID: 5}})
+	if _, ok := n.peerAdaptation(5, false); ok {
+		t.Error("adaptation indication kept after SCTP_COMM_LOST")
+	}
+}
+
+func TestNotifierOverflow(t *testing.T) {
+	a, b := &Notification{}, &Notification{}
+	ctx := context.Background()
+
+	n := newNotifier(1, NotificationBlock)
+	if err := n.deliver(ctx, time.Time{}, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.deliver(ctx, time.Now().Add(20*time.Millisecond), b); err != os.ErrDeadlineExceeded {
+		t.Fatalf("got %v, expected the deadline to expire", err)
+	}
+	if got := <-n.ch; got != a {
+		t.Fatalf("got %p, expected %p", got, a)
+	}
+	// the notification that timed out is kept, not lost
+	if err := n.deliver(ctx, time.Time{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-n.ch; got != b {
+		t.Fatalf("got %p, expected %p", got, b)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		n.close()
+	}()
+	n.deliver(ctx, time.Time{}, a)
+	if err := n.deliver(ctx, time.Time{}, b); err != net.ErrClosed {
+		t.Fatalf("got %v, expected net.ErrClosed", err)
+	}
+	for range n.ch {
+	}
+
+	n = newNotifier(1, NotificationDrop)
+	for i := 0; i < 3; i++ {
+		if err := n.deliver(ctx, time.Time{}, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n.dropped != 2 {
+		t.Errorf("dropped %d notifications, expected 2", n.dropped)
+	}
+}
+
+func TestMessageTooLong(t *testing.T) {
+	c, _ := socketpairConn(t)
+
+	// a datagram larger than the send buffer fails with EMSGSIZE
+	err := c.fd.control(func(s int) error {
+		return syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_SNDBUF, 4096)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Write(make([]byte, 1<<20))
+	var tooLong *MessageTooLongError
+	if !errors.As(err, &tooLong) || tooLong.Size != 1<<20 {
+		t.Fatalf("got %v", err)
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) {
+		t.Errorf("%v does not wrap EMSGSIZE", err)
+	}
+}
+
+func TestAcceptPeelOffKeepsCommUp(t *testing.T) {
+	c, _ := socketpairConn(t)
+	ln := &SCTPListener{
+		SCTPConn:   *c,
+		socketMode: OneToMany,
+		peelOff:    &autoPeelOff{sem: make(chan struct{}, 1)},
+	}
+	if err := ln.EnableNotifications(0, NotificationBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	type assocChange struct {
+		Type, Flags                     uint16
+		Length                          uint32
+		State, Error, Outbound, Inbound uint16
+		AssocID                         int32
+	}
+	b := toBuf(assocChange{Type: uint16(SCTP_ASSOC_CHANGE), State: uint16(SCTP_COMM_UP), AssocID: 9})
+	nativeEndian.PutUint32(b[4:], uint32(len(b)))
+	commUp, err := SCTPParseNotification(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an accept given up while passing SCTP_COMM_UP on, which it had
+	// already read from the socket
+	ln.peelOff.notif = commUp
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ln.deliverNotification(ctx, commUp); err != context.DeadlineExceeded {
+		t.Fatalf("got %v", err)
+	}
+
+	// the next accept passes it on once and goes on to peel the
+	// association off, which a socketpair cannot do
+	got := make(chan *Notification, 2)
+	go func() {
+		for n := range ln.Notifications() {
+			got <- n
+		}
+	}()
+	if _, err := ln.acceptPeelOff(context.Background()); err == nil {
+		t.Error("peeled off from a socketpair")
+	}
+	if ln.peelOff.notif != nil {
+		t.Error("notification still pending")
+	}
+	if n := <-got; n != commUp {
+		t.Errorf("got %+v", n)
+	}
+	select {
+	case n := <-got:
+		t.Errorf("got %+v twice", n)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSCTPPeekMsg(t *testing.T) {
+	c, peer := socketpairConn(t)
+
+	if _, err := syscall.Write(peer, []byte("early data")); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		flags int
+		info  *RecvMsgInfo
+	)
+	err := c.fd.control(func(s int) (err error) {
+		flags, info, err = sctpPeekMsg(s)
+		return
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&MSG_NOTIFICATION != 0 || info == nil {
+		t.Errorf("got flags %#x, info %+v", flags, info)
+	}
+	// peeking leaves the message in place
+	buf := make([]byte, 64)
+	if n, err := c.Read(buf); err != nil || string(buf[:n]) != "early data" {
+		t.Errorf("got %q, %v", buf[:n], err)
+	}
+}
+
+func TestAssociationManagerTable(t *testing.T) {
+	m := newAssociationManager(nil, 2)
+	a := m.add(AssociationInfo{ID: 3, State: SCTP_ESTABLISHED})
+	if m.add(AssociationInfo{ID: 3}) != a {
+		t.Fatal("association added twice")
+	}
+	if got, err := m.Accept(); err != nil || got != a {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	// a full queue drops messages instead of holding up the reader
+	a.queue(&assocMsg{data: []byte("hello world"), info: &RcvInfo{AssocID: 3}})
+	a.queue(&assocMsg{data: []byte("second"), info: &RcvInfo{AssocID: 3}})
+	a.queue(&assocMsg{data: []byte("dropped"), info: &RcvInfo{AssocID: 3}})
+	if n := a.Dropped(); n != 1 {
+		t.Errorf("got %d dropped messages", n)
+	}
+	m.remove(3)
+	if m.Association(3) != nil || len(m.Associations()) != 0 {
+		t.Error("association still in table")
+	}
+	if state := a.Info().State; state != SCTP_CLOSED {
+		t.Errorf("got state %v", state)
+	}
+
+	// the queued message is read before io.EOF
+	buf := make([]byte, 6)
+	n, info, flags, err := a.ReadMsg(buf)
+	if err != nil || string(buf[:n]) != "hello " || flags&MSG_EOR != 0 || info.AssocID != 3 {
+		t.Errorf("got %q, %+v, %#x, %v", buf[:n], info, flags, err)
+	}
+	n, _, flags, err = a.ReadMsg(buf)
+	if err != nil || string(buf[:n]) != "world" || flags&MSG_EOR == 0 {
+		t.Errorf("got %q, %#x, %v", buf[:n], flags, err)
+	}
+	if n, err := a.Read(buf); err != nil || string(buf[:n]) != "second" {
+		t.Errorf("got %q, %v", buf[:n], err)
+	}
+	if _, err := a.Read(buf); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.AcceptContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+
+	// two additions leave a single wakeup; the accept it wakes up passes
+	// it on for the other association
+	m.add(AssociationInfo{ID: 5})
+	m.add(AssociationInfo{ID: 6})
+	<-m.ready
+	if a, err := m.Accept(); err != nil || a.ID() != 5 {
+		t.Fatalf("got %v, %v", a, err)
+	}
+	select {
+	case <-m.ready:
+	default:
+		t.Error("no wakeup left for the second association")
+	}
+
+	// read deadlines would end the reads of the manager
+	ln := &SCTPListener{managed: 1}
+	if err := ln.SetReadDeadline(time.Now()); err == nil {
+		t.Error("read deadline set on managed listener")
+	}
+	if err := ln.SetDeadline(time.Now()); err == nil {
+		t.Error("deadline set on managed listener")
+	}
+}
+
+func TestReassembly(t *testing.T) {
+	r := make(reassembly)
+	s1 := &RcvInfo{AssocID: 3, Stream: 1}
+	s2 := &RcvInfo{AssocID: 3, Stream: 2}
+	u1 := &RcvInfo{AssocID: 3, Stream: 1, Flags: SCTP_UNORDERED}
+	other := &RcvInfo{AssocID: 4, Stream: 1}
+
+	// pieces of messages on different streams, of unordered messages and
+	// of other associations come in mixed with fragment interleave 2
+	for _, p := range []struct {
+		info *RcvInfo
+		data string
+		eor  bool
+		want string
+	}{
+		{s1, "stream ", false, ""},
+		{s2, "two ", false, ""},
+		{u1, "unordered ", false, ""},
+		{other, "other ", false, ""},
+		{s1, "one", true, "stream one"},
+		{other, "association", true, "other association"},
+		{u1, "one", true, "unordered one"},
+		{s2, "done", true, "two done"},
+	} {
+		msg := r.add(p.info, []byte(p.data), p.eor)
+		if p.want == "" {
+			if msg != nil {
+				t.Errorf("got message %q before its end", msg.data)
+			}
+			continue
+		}
+		if msg == nil || string(msg.data) != p.want || msg.info != p.info {
+			t.Errorf("got %+v, want %q", msg, p.want)
+		}
+	}
+	if len(r) != 0 {
+		t.Errorf("%d messages left", len(r))
+	}
+
+	// the pieces of an association that is gone do not end up in front
+	// of the messages of another one reusing its ID
+	r.add(s1, []byte("stale"), false)
+	r.add(s2, []byte("stale"), false)
+	r.add(other, []byte("kept"), false)
+	r.drop(3)
+	if msg := r.add(s1, []byte("fresh"), true); msg == nil || string(msg.data) != "fresh" {
+		t.Errorf("got %+v", msg)
+	}
+	if len(r) != 1 {
+		t.Errorf("%d messages left", len(r))
+	}
+}
+
+func TestSCTPConnNotifications(t *testing.T) {
+	c, peer := socketpairConn(t)
+
+	if c.Notifications() != nil {
+		t.Fatal("notifications enabled by default")
+	}
+	if err := c.EnableNotifications(4, NotificationBlock); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EnableNotifications(4, NotificationBlock); err == nil {
+		t.Error("notifications enabled twice")
+	}
+	ch := c.Notifications()
+
+	// data still reaches the reader
+	if _, err := syscall.Write(peer, []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	if n, err := c.Read(buf); err != nil || string(buf[:n]) != "data" {
+		t.Fatalf("got %q, %v", buf[:n], err)
+	}
+
+	c.Close()
+	if _, ok := <-ch; ok {
+		t.Error("notification channel still open after close")
+	}
+	if err := c.EnableNotifications(4, NotificationBlock); err != net.ErrClosed {
+		t.Errorf("got %v, expected net.ErrClosed", err)
+	}
+}
+
+func TestSockoptLayout(t *testing.T) {
+	// sizes of the kernel structs behind the options
+	for _, c := range []struct {
+		v    interface{}
+		size int
+	}{
+		{PRInfo{}, 8},
+		{defaultPRInfo{}, 12},
+		{PRStatus{}, 24},
+		{assocValue{}, 8},
+		{addStreams{}, 8},
+		{authKeyID{}, 8},
+		{streamValue{}, 8},
+		{paddrParams{}, 156},
+		{paddrThlds{}, 144},
+		{rtoInfo{}, 16},
+		{assocParams{}, 20},
+		{sackInfo{}, 12},
+	} {
+		if n := len(toBuf(c.v)); n != c.size {
+			t.Errorf("%T encodes to %d bytes, expected %d", c.v, n, c.size)
+		}
+	}
+
+	raddr := &SCTPAddr{IPAddrs: []net.IPAddr{{IP: net.IPv4(192, 0, 2, 1)}, {IP: net.IPv4(192, 0, 2, 2)}}, Port: 5000}
+	st := sockaddrStorage(raddr)
+	buf := toBuf(paddrParams{AssocID: 7, Address: st, PathMaxRxt: 3, Flags: SPP_HB_DEMAND})
+	if id := int32(nativeEndian.Uint32(buf)); id != 7 {
+		t.Errorf("got assoc id %d", id)
+	}
+	if ip, port, err := parseSockaddr(buf[4:]); err != nil || !ip.IP.Equal(raddr.IPAddrs[0].IP) || port != 5000 {
+		t.Errorf("got address %v port %d: %v", ip, port, err)
+	}
+	if rxt := nativeEndian.Uint16(buf[136:]); rxt != 3 {
+		t.Errorf("got path max rxt %d", rxt)
+	}
+	if flags := nativeEndian.Uint32(buf[146:]); flags != SPP_HB_DEMAND {
+		t.Errorf("got flags %#x", flags)
+	}
+	buf = toBuf(paddrThlds{AssocID: 7, Address: st, PathPFThld: 2})
+	if ip, port, err := parseSockaddr(buf[8:]); err != nil || !ip.IP.Equal(raddr.IPAddrs[0].IP) || port != 5000 {
+		t.Errorf("got thresholds address %v port %d: %v", ip, port, err)
+	}
+	if pf := nativeEndian.Uint16(buf[138:]); pf != 2 {
+		t.Errorf("got pf threshold %d", pf)
+	}
+	if size := unsafe.Sizeof(paddrThlds{}); size != 144 {
+		t.Errorf("paddrThlds is %d bytes in memory", size)
+	}
+	if sockaddrStorage(nil) != [128]byte{} {
+		t.Error("nil address should encode to zero sockaddr_storage")
+	}
+
+	msgs, err := SCTPParseOOBMessages(sctpCmsg(SCTP_CMSG_PRINFO, toBuf(PRInfo{Policy: SCTP_PR_SCTP_TTL, Value: 100})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Type() != SCTP_CMSG_PRINFO {
+		t.Fatalf("got %+v", msgs)
+	}
+	if policy := PRPolicy(nativeEndian.Uint16(msgs[0].Data)); policy != SCTP_PR_SCTP_TTL {
+		t.Errorf("got policy %#x", policy)
+	}
+	if value := nativeEndian.Uint32(msgs[0].Data[4:]); value != 100 {
+		t.Errorf("got value %d", value)
+	}
+}
+
+func TestPRPolicyValues(t *testing.T) {
+	// values of include/uapi/linux/sctp.h; SCTP_PR_SCTP_ALL is a bit of
+	// its own, above SCTP_PR_SCTP_MASK
+	for _, c := range []struct {
+		policy PRPolicy
+		value  uint16
+	}{
+		{SCTP_PR_SCTP_NONE, 0x00},
+		{SCTP_PR_SCTP_TTL, 0x10},
+		{SCTP_PR_SCTP_RTX, 0x20},
+		{SCTP_PR_SCTP_PRIO, 0x30},
+		{SCTP_PR_SCTP_ALL, 0x80},
+	} {
+		if uint16(c.policy) != c.value {
+			t.Errorf("policy %#x, expected %#x", uint16(c.policy), c.value)
+		}
+	}
+}
+
+func TestSCTPEventFlags(t *testing.T) {
+	var all int
+	for n := SCTP_ASSOC_CHANGE; n <= SCTP_SEND_FAILED_EVENT; n++ {
+		flag := n.eventFlag()
+		if flag == 0 || flag&SCTP_EVENT_ALL != flag {
+			t.Errorf("%v: invalid event flag %#x", n, flag)
+		}
+		if all&flag != 0 {
+			t.Errorf("%v: event flag %#x used twice", n, flag)
+		}
+		all |= flag
+	}
+	if all|SCTP_EVENT_DATA_IO != SCTP_EVENT_ALL {
+		t.Errorf("notification types cover %#x, expected %#x", all|SCTP_EVENT_DATA_IO, SCTP_EVENT_ALL)
+	}
+}
+
 var sctpListenerNameTests = []*SCTPAddr{
 	&SCTPAddr{IPAddrs: []net.IPAddr{net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}}},
 	&SCTPAddr{},
@@ -186,7 +1023,7 @@ func TestSCTPCloseRecv(t *testing.T) {
 		buf := make([]byte, 256)
 		_, xerr = conn.Read(buf)
 		t.Logf("got error while read: %v", xerr)
-		if xerr != io.EOF && xerr != syscall.EBADF {
+		if xerr != net.ErrClosed {
 			t.Fatalf("read failed: %v", xerr)
 		}
 	}()
@@ -208,6 +1045,301 @@ func TestSCTPCloseRecv(t *testing.T) {
 	wg.Wait()
 }
 
+// socketpairConn returns an SCTPConn on one end of a socketpair, which
+// stands in for an association in tests that do not depend on the
+// protocol, and the descriptor of the other end. Both are closed when the
+// test ends.
+func socketpairConn(t *testing.T) (*SCTPConn, int) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { syscall.Close(fds[1]) })
+	c, err := newSCTPConn(fds[0], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, fds[1]
+}
+
+func TestSCTPConnCloseLifecycle(t *testing.T) {
+	// the descriptor lifecycle does not depend on the protocol
+	c, _ := socketpairConn(t)
+
+	res := make(chan error, 1)
+	go func() {
+		_, err := c.Read(make([]byte, 64))
+		res <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	select {
+	case err := <-res:
+		if err != net.ErrClosed {
+			t.Fatalf("expected %v from blocked read, got: %v", net.ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked read was not unblocked by Close")
+	}
+
+	if err := c.Close(); err != net.ErrClosed {
+		t.Fatalf("expected %v from second close, got: %v", net.ErrClosed, err)
+	}
+	if _, err := c.Write([]byte{0}); err != net.ErrClosed {
+		t.Fatalf("expected %v from write after close, got: %v", net.ErrClosed, err)
+	}
+	if err := c.SetEvents(SCTP_EVENT_DATA_IO); err != net.ErrClosed {
+		t.Fatalf("expected %v from sockopt after close, got: %v", net.ErrClosed, err)
+	}
+	if fd := c.FD(); fd != -1 {
+		t.Fatalf("expected FD() to return -1 after close, got: %d", fd)
+	}
+}
+
+func TestSCTPReadDeadline(t *testing.T) {
+	addr, _ := ResolveSCTPAddr(SCTP4, "127.0.0.1:0")
+	ln, err := NewSCTPListener(addr, InitMsg{}, OneToOne, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	raddr, err := ln.SCTPLocalAddr(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewSCTPConnection(raddr.AddressFamily, InitMsg{}, OneToOne, false)
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	defer c.Close()
+	if err := c.Connect(raddr); err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+
+	conn, err := ln.AcceptSCTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Read(make([]byte, 64))
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+}
+
+func TestSCTPAcceptDeadline(t *testing.T) {
+	addr, _ := ResolveSCTPAddr(SCTP4, "127.0.0.1:0")
+	ln, err := NewSCTPListener(addr, InitMsg{}, OneToOne, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if err := ln.SetDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ln.Accept()
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+}
+
+func TestOneToManyReadDeadline(t *testing.T) {
+	addr, _ := ResolveSCTPAddr(SCTP4, "127.0.0.1:0")
+	ln, err := NewSCTPListener(addr, InitMsg{}, OneToMany, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if err := ln.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err = ln.SCTPRead(make([]byte, 64))
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+}
+
+func TestDialSCTP(t *testing.T) {
+	ln, err := ListenSCTP("sctp4", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	laddr := ln.Addr().(*SCTPAddr)
+	raddr, _ := ResolveSCTPAddr(SCTP4, fmt.Sprintf("127.0.0.1:%d", laddr.Port))
+
+	d := SCTPDialer{Timeout: time.Second, HeartbeatInterval: 5 * time.Second}
+	c, err := d.Dial("sctp", raddr.String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := c.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "ping" {
+		t.Fatalf("got %q, expected %q", buf[:n], "ping")
+	}
+}
+
+func TestDialSCTPUnknownNetwork(t *testing.T) {
+	raddr, _ := ResolveSCTPAddr(SCTP4, "127.0.0.1:1")
+	if _, err := DialSCTP("tcp", nil, raddr); err == nil {
+		t.Fatal("expected error for unknown network")
+	}
+	if _, err := ListenSCTP("udp", nil); err == nil {
+		t.Fatal("expected error for unknown network")
+	}
+}
+
+func TestSCTPSyscallConn(t *testing.T) {
+	addr, _ := ResolveSCTPAddr(SCTP4, "127.0.0.1:0")
+	ln, err := NewSCTPListener(addr, InitMsg{}, OneToMany, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := ln.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sotype int
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		sotype, serr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TYPE)
+	})
+	if err != nil || serr != nil {
+		t.Fatalf("control failed: %v, %v", err, serr)
+	}
+	if sotype != syscall.SOCK_SEQPACKET {
+		t.Fatalf("got socket type %d, expected %d", sotype, syscall.SOCK_SEQPACKET)
+	}
+
+	ln.Close()
+	if err := rc.Control(func(fd uintptr) {}); err == nil {
+		t.Fatal("expected error from Control on closed listener")
+	}
+}
+
+func TestSCTPReadMsgContext(t *testing.T) {
+	addr, _ := ResolveSCTPAddr(SCTP4, "127.0.0.1:0")
+	ln, err := NewSCTPListener(addr, InitMsg{}, OneToOne, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ln.AcceptContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got: %v", context.DeadlineExceeded, err)
+	}
+
+	raddr, err := ln.SCTPLocalAddr(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := DialSCTP("sctp", nil, raddr)
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	defer c.Close()
+
+	conn, err := ln.AcceptSCTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	buf := make([]byte, 64)
+	if _, _, _, err := conn.ReadMsgContext(ctx, buf); err != context.Canceled {
+		t.Fatalf("expected %v, got: %v", context.Canceled, err)
+	}
+
+	// the association must survive the cancellation
+	if _, err := c.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "ping" {
+		t.Fatalf("got %q, expected %q", buf[:n], "ping")
+	}
+}
+
+func TestPollDeadlineCancel(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	d := pollDeadline{set: r.SetReadDeadline}
+	read := func() error {
+		_, err := r.Read(make([]byte, 1))
+		return err
+	}
+
+	// an operation that is not cancelled must keep waiting while another
+	// one is interrupted
+	res := make(chan error, 1)
+	go func() { res <- d.wait(context.Background(), read) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := d.wait(ctx, read); err != context.Canceled {
+		t.Fatalf("expected %v, got: %v", context.Canceled, err)
+	}
+
+	select {
+	case err := <-res:
+		t.Fatalf("uncancelled read returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Write([]byte{0})
+	if err := <-res; err != nil {
+		t.Fatalf("uncancelled read failed: %v", err)
+	}
+
+	if err := d.setDeadline(time.Now().Add(20 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.wait(context.Background(), read); !os.IsTimeout(err) {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+}
+
 func TestSCTPConcurrentOneToMany(t *testing.T) {
 	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
 	addr, _ := ResolveSCTPAddr(SCTP4, "127.0.0.1:0")
@@ -452,7 +1584,7 @@ func TestNonBlockingServerOneToMany(t *testing.T) {
 			SndRcvInfo *SndRcvInfo
 			Data       []byte
 		}
-		b := make(map[int32]map[uint16]bytes.Buffer)
+		b := make(map[AssocID]map[uint16]bytes.Buffer)
 		c := make([]*ready, 0)
 		for {
 			buf := make([]byte, 64)